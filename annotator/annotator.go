@@ -0,0 +1,639 @@
+package annotator
+
+import (
+	"fmt"
+	"grandanno/cnv"
+	"grandanno/data"
+	"grandanno/snv"
+	"log"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Annotator 注释器：持有注释所需的数据库信息，可在其他Go程序中直接调用；
+// MarkOffPanel为true时不在panel中的变异标记后输出，否则丢弃。
+// 数据库文件及参数配置保存在注释器中，但染色体列表、别名及PAR为进程内全局状态(data.Config)，
+// 创建注释器时会被重置，因此同一进程中同时只支持一个注释器(或同一配置及基因组版本的多个注释器)
+type Annotator struct {
+	DBPath       string
+	SplicingLen  int
+	Threads      int
+	Phase        bool
+	Sexes        map[string]string
+	Pedigree     data.Pedigree
+	Zygosities   []string
+	MarkOffPanel bool
+	Output       Output
+	genePanel    *data.GenePanel
+	bedRegions   data.BedRegions
+	refgenes     data.Refgenes
+	index        data.RefgeneIndex
+	reference    *data.Faidx
+	refFile      string
+	refOnce      sync.Once
+	refErr       error
+	population   *data.VcfDB
+	clinvar      *data.ClinvarDB
+	clinvarOnce  sync.Once
+	clinvarErr   error
+	dbsnp        *data.VcfDB
+	config       data.Configuration
+}
+
+// GetRefgeneFiles 获取转录本文件列表(RefGene/GTF/GFF3)，未配置的文件跳过
+func GetRefgeneFiles(dbPath string, dbFile data.DBFileConfig) []string {
+	var refgeneFiles []string
+	for _, name := range []string{dbFile.Refgene, dbFile.EnsMt} {
+		if name != "" {
+			refgeneFiles = append(refgeneFiles, path.Join(dbPath, name))
+		}
+	}
+	return refgeneFiles
+}
+
+// readConfig 读取配置文件并返回读取到的配置，配置了chrom_alias时读取染色体别名文件
+func readConfig(dbPath string, configFile string, build string) (data.Configuration, error) {
+	if err := data.ReadConfigYAML(configFile, build); err != nil {
+		return data.Config, err
+	}
+	if data.Config.DBFile.ChromAlias == "" {
+		return data.Config, nil
+	}
+	return data.Config, data.ReadChromAliasFile(path.Join(dbPath, data.Config.DBFile.ChromAlias))
+}
+
+// readRefgeneFiles 读取转录本文件，配置了ens_mt时线粒体转录本只从ens_mt读取
+func readRefgeneFiles(dbPath string, dbFile data.DBFileConfig) (data.Refgenes, error) {
+	mtFile := ""
+	if dbFile.EnsMt != "" {
+		mtFile = path.Join(dbPath, dbFile.EnsMt)
+	}
+	return data.ReadRefgeneFiles(GetRefgeneFiles(dbPath, dbFile), mtFile)
+}
+
+// Prepare 对数据库文件进行预处理，生成参考基因组.fai索引及mRNA序列文件。build为基因组版本，为空时使用配置文件中的版本
+func Prepare(dbPath string, configFile string, build string) error {
+	config, err := readConfig(dbPath, configFile, build)
+	if err != nil {
+		return err
+	}
+	dbFile := config.DBFile
+	referenceFile := path.Join(dbPath, dbFile.Reference)
+	if err := data.PrepareFaidx(referenceFile); err != nil {
+		return err
+	}
+	reference, err := data.OpenFaidx(referenceFile)
+	if err != nil {
+		return err
+	}
+	defer reference.Close()
+	refgenes, err := readRefgeneFiles(dbPath, dbFile)
+	if err != nil {
+		return err
+	}
+	if err := data.WriteMrnaFile(path.Join(dbPath, dbFile.Mrna), refgenes, reference); err != nil {
+		return err
+	}
+	if dbFile.AnnoDB != "" {
+		if err := writeAnnoDB(dbPath, dbFile, refgenes, reference); err != nil {
+			return err
+		}
+	}
+	manifest, err := data.NewManifest(dbPath)
+	if err != nil {
+		return err
+	}
+	return data.WriteManifestFile(path.Join(dbPath, data.ManifestFile), manifest)
+}
+
+// Validate 检查数据库文件与pre生成的清单是否一致，以及mRNA序列长度与转录本区间是否一致，返回不一致的描述
+func Validate(dbPath string, configFile string, build string) (problems []string, err error) {
+	config, err := readConfig(dbPath, configFile, build)
+	if err != nil {
+		return
+	}
+	dbFile := config.DBFile
+	manifest, err := data.ReadManifestFile(path.Join(dbPath, data.ManifestFile))
+	if os.IsNotExist(err) {
+		problems = append(problems, data.ManifestFile+": file is missing, please rerun pre")
+	} else if err != nil {
+		return
+	} else {
+		var manifestProblems []string
+		if manifestProblems, err = manifest.Check(dbPath); err != nil {
+			return
+		}
+		problems = append(problems, manifestProblems...)
+	}
+	err = nil
+	if dbFile.AnnoDB != "" {
+		header, e := data.ReadAnnoDBHeader(path.Join(dbPath, dbFile.AnnoDB))
+		if e == nil {
+			e = header.Check(dbPath, true)
+		}
+		if e != nil && !os.IsNotExist(e) {
+			problems = append(problems, dbFile.AnnoDB+": "+e.Error())
+		}
+	}
+	refgenes, err := readRefgeneFiles(dbPath, dbFile)
+	if err != nil {
+		return
+	}
+	lengths, err := data.ReadFastaLengths(path.Join(dbPath, dbFile.Mrna))
+	if err != nil {
+		return
+	}
+	for _, refgene := range refgenes {
+		expected := refgene.ExonEnd - refgene.ExonStart + 1
+		if length, ok := lengths[refgene.GetSn()]; ok && length != expected {
+			problems = append(problems, fmt.Sprintf("%s: mRNA length %d, expected %d", refgene.GetSn(), length, expected))
+		}
+	}
+	return
+}
+
+// writeAnnoDB 生成二进制注释数据库文件
+func writeAnnoDB(dbPath string, dbFile data.DBFileConfig, refgenes data.Refgenes, reference *data.Faidx) error {
+	ncbiGeneFile := path.Join(dbPath, dbFile.NcbiGene)
+	ncbiGene, err := data.ReadNCBIGeneInfo(ncbiGeneFile)
+	if err != nil {
+		return err
+	}
+	if err := refgenes.SetEntrezidAndReference(ncbiGene, reference); err != nil {
+		return err
+	}
+	sourceFiles := append([]string{reference.File, ncbiGeneFile}, GetRefgeneFiles(dbPath, dbFile)...)
+	db, err := data.NewAnnoDB(refgenes, sourceFiles)
+	if err != nil {
+		return err
+	}
+	return data.WriteAnnoDBFile(path.Join(dbPath, dbFile.AnnoDB), db)
+}
+
+// loadAnnoDB 读取二进制注释数据库文件并检查与当前配置是否一致；指定了基因时读取NCBI GENE INFO创建基因panel，
+// 限制了基因或区域时只保留panel中的转录本并重建索引
+func (annotator *Annotator) loadAnnoDB(genes []string) error {
+	dbFile := annotator.config.DBFile
+	db, err := data.ReadAnnoDBFile(path.Join(annotator.DBPath, dbFile.AnnoDB))
+	if err != nil {
+		return err
+	}
+	if err := db.Header.Check(annotator.DBPath, false); err != nil {
+		return err
+	}
+	if len(genes) > 0 {
+		ncbiGene, err := data.ReadNCBIGeneInfo(path.Join(annotator.DBPath, dbFile.NcbiGene))
+		if err != nil {
+			return err
+		}
+		annotator.setGenePanel(genes, ncbiGene)
+	}
+	if !annotator.hasPanel() {
+		annotator.refgenes, annotator.index = db.Refgenes, db.Index
+		return nil
+	}
+	annotator.refgenes = annotator.filterRefgenes(db.Refgenes)
+	annotator.index = data.NewRefgeneIndex(annotator.refgenes)
+	return nil
+}
+
+// loadRefgenes 读取转录本文件，并添加Entrez ID及序列信息；指定了基因时创建基因panel，
+// 限制了基因或区域时先只保留panel中的转录本，再为其设置序列
+func (annotator *Annotator) loadRefgenes(genes []string) error {
+	dbPath, dbFile := annotator.DBPath, annotator.config.DBFile
+	var ncbiGene data.NcbiGene
+	var mrna data.Fasta
+	errChan := make(chan error, 2)
+	go func() {
+		var err error
+		ncbiGene, err = data.ReadNCBIGeneInfo(path.Join(dbPath, dbFile.NcbiGene))
+		errChan <- err
+	}()
+	go func() {
+		var err error
+		mrna, err = data.ReadFastaFile(path.Join(dbPath, dbFile.Mrna))
+		errChan <- err
+	}()
+	refgenes, err := readRefgeneFiles(dbPath, dbFile)
+	for i := 0; i < cap(errChan); i++ {
+		if e := <-errChan; e != nil && err == nil {
+			err = e
+		}
+	}
+	if err != nil {
+		return err
+	}
+	if len(genes) > 0 {
+		annotator.setGenePanel(genes, ncbiGene)
+	}
+	if annotator.hasPanel() {
+		refgenes.SetEntrezid(ncbiGene)
+		refgenes = annotator.filterRefgenes(refgenes)
+	}
+	refgenes.SetEntrezidAndSequence(ncbiGene, mrna)
+	annotator.refgenes, annotator.index = refgenes, data.NewRefgeneIndex(refgenes)
+	return nil
+}
+
+// NewAnnotator 读取配置文件及数据库文件，创建注释器。build为基因组版本，为空时使用配置文件中的版本；
+// 读取配置文件会重置进程内的染色体列表、别名及PAR，见Annotator
+func NewAnnotator(dbPath string, configFile string, build string) (*Annotator, error) {
+	return NewPanelAnnotator(dbPath, configFile, build, nil, "")
+}
+
+// NewPanelAnnotator 创建只注释指定基因及区域的注释器：genes为Gene symbol或Entrez ID(通过NCBI GENE INFO转换)，bedFile为BED文件，
+// 均为空时与NewAnnotator相同；只加载panel基因中与BED区域重叠的转录本，参考基因组及ClinVar在首次注释SNV时读取
+func NewPanelAnnotator(dbPath string, configFile string, build string, genes []string, bedFile string) (*Annotator, error) {
+	config, err := readConfig(dbPath, configFile, build)
+	if err != nil {
+		return nil, err
+	}
+	dbFile := config.DBFile
+	annotator := &Annotator{
+		DBPath:      dbPath,
+		SplicingLen: config.Param.SplicingLen,
+		Threads:     1,
+		Output:      Output{Format: FormatJSON},
+		config:      config,
+	}
+	if bedFile != "" {
+		if annotator.bedRegions, err = data.ReadBedFile(bedFile); err != nil {
+			return nil, err
+		}
+	}
+	_, e := os.Stat(path.Join(dbPath, dbFile.AnnoDB))
+	if dbFile.AnnoDB != "" && e != nil {
+		log.Printf("skip anno_db: %v, read source files instead, please run pre to generate it\n", e)
+	}
+	if dbFile.AnnoDB != "" && e == nil {
+		err = annotator.loadAnnoDB(genes)
+	} else {
+		err = annotator.loadRefgenes(genes)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if dbFile.Population != "" {
+		if annotator.population, err = data.OpenVcfDB(path.Join(dbPath, dbFile.Population)); err != nil {
+			return nil, err
+		}
+	}
+	if dbFile.Dbsnp != "" {
+		if annotator.dbsnp, err = data.OpenVcfDB(path.Join(dbPath, dbFile.Dbsnp)); err != nil {
+			annotator.Close()
+			return nil, err
+		}
+	}
+	referenceFile := path.Join(dbPath, dbFile.Reference)
+	if _, e := os.Stat(referenceFile); dbFile.Reference != "" && e == nil {
+		annotator.refFile = referenceFile
+	}
+	return annotator, nil
+}
+
+// Close 关闭注释器打开的数据库文件
+func (annotator *Annotator) Close() error {
+	var err error
+	for _, db := range []*data.VcfDB{annotator.population, annotator.dbsnp} {
+		if db != nil {
+			if e := db.Close(); e != nil {
+				err = e
+			}
+		}
+	}
+	if annotator.reference != nil {
+		if e := annotator.reference.Close(); e != nil {
+			err = e
+		}
+	}
+	return err
+}
+
+// setGenePanel 通过NCBI GENE INFO将基因转换为基因panel
+func (annotator *Annotator) setGenePanel(genes []string, ncbiGene data.NcbiGene) {
+	panel := data.NewGenePanel(genes, ncbiGene)
+	annotator.genePanel = &panel
+}
+
+// filterRefgenes 只保留panel基因中与BED区域重叠的转录本，转录本需已设置Entrez ID
+func (annotator *Annotator) filterRefgenes(refgenes data.Refgenes) data.Refgenes {
+	panelRefgenes := make(data.Refgenes, 0)
+	for _, refgene := range refgenes {
+		if annotator.genePanel != nil && !annotator.genePanel.Contains(refgene) ||
+			annotator.bedRegions != nil && !annotator.bedRegions.Overlaps(refgene.Chrom, refgene.ExonStart, refgene.ExonEnd) {
+			continue
+		}
+		panelRefgenes = append(panelRefgenes, refgene)
+	}
+	log.Printf("%d of %d transcripts are in panel\n", len(panelRefgenes), len(refgenes))
+	return panelRefgenes
+}
+
+// IsOnPanel 变异是否在panel中：指定了基因时需与panel中转录本(不包括上下游区)重叠，指定了BED时需与BED区域重叠
+func (annotator *Annotator) IsOnPanel(variant data.Variant) bool {
+	if annotator.genePanel != nil {
+		found := false
+		for _, refgene := range annotator.GetRefgenes(variant) {
+			if refgene.ExonStart <= variant.End && variant.Start <= refgene.ExonEnd {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return annotator.bedRegions == nil || annotator.bedRegions.Overlaps(variant.Chrom, variant.Start, variant.End)
+}
+
+// hasPanel 是否限制了注释的基因或区域
+func (annotator *Annotator) hasPanel() bool {
+	return annotator.genePanel != nil || annotator.bedRegions != nil
+}
+
+// GetRefgenes 获取与变异区域(包括上下游区域)重叠的Refgene
+func (annotator *Annotator) GetRefgenes(variant data.Variant) data.Refgenes {
+	return annotator.index.FindRefgenes(variant.Chrom, variant.Start, variant.End)
+}
+
+// getReference 获取用于SNV标准化的参考基因组，首次调用时打开；未配置参考基因组时返回nil
+func (annotator *Annotator) getReference() (*data.Faidx, error) {
+	annotator.refOnce.Do(func() {
+		if annotator.refFile != "" {
+			annotator.reference, annotator.refErr = data.OpenFaidx(annotator.refFile)
+		}
+	})
+	return annotator.reference, annotator.refErr
+}
+
+// ShiftSnv 在参考基因组上将插入/缺失分别移位到正链及负链的3'端并判断插入是否为重复，未配置参考基因组或非插入/缺失时返回原SNV
+func (annotator *Annotator) ShiftSnv(variant snv.Snv) (snv.Snv, error) {
+	reference, err := annotator.getReference()
+	if err != nil {
+		return nil, err
+	}
+	if reference == nil || !variant.GetVariant().IsIndel() {
+		return variant, nil
+	}
+	forward, err := variant.GetVariant().RightAlign(reference)
+	if err != nil {
+		return nil, err
+	}
+	backward, err := variant.GetVariant().LeftAlign(reference)
+	if err != nil {
+		return nil, err
+	}
+	shifted := snv.ShiftedSnv{Snv: variant, Forward: forward, Backward: backward}
+	if shifted.ForwardDup, err = forward.IsDuplication(reference, true); err != nil {
+		return nil, err
+	}
+	if shifted.BackwardDup, err = backward.IsDuplication(reference, false); err != nil {
+		return nil, err
+	}
+	return shifted, nil
+}
+
+// AnnotateSnv 注释单个SNV，variant为ShiftedSnv时每个转录本使用其方向上3'端移位后的变异
+func (annotator *Annotator) AnnotateSnv(variant snv.Snv) snv.Annotations {
+	refgenes := annotator.GetRefgenes(variant.GetVariant())
+	if shifted, ok := variant.(snv.ShiftedSnv); ok {
+		refgenes = annotator.index.FindRefgenes(shifted.Backward.Chrom, shifted.Backward.Start, shifted.Forward.End)
+	}
+	return snv.NewAnnotations(variant, refgenes, annotator.SplicingLen)
+}
+
+// AnnotateCnv 注释单个CNV
+func (annotator *Annotator) AnnotateCnv(variant cnv.Cnv) cnv.Annotations {
+	refgenes := annotator.GetRefgenes(variant.GetVariant())
+	return cnv.NewAnnotations(variant, refgenes)
+}
+
+// GetFrequencies 获取变异的人群频率，未配置人群频率数据库时返回nil
+func (annotator *Annotator) GetFrequencies(variant data.Variant) (map[string]data.Frequency, error) {
+	if annotator.population == nil {
+		return nil, nil
+	}
+	matches, err := annotator.population.FindVariant(variant)
+	if err != nil {
+		return nil, err
+	}
+	return data.GetFrequencies(matches, annotator.config.Param.Populations), nil
+}
+
+// GetDbsnp 获取变异的dbSNP rsID，多个rsID以";"分隔
+func (annotator *Annotator) GetDbsnp(variant data.Variant) (string, error) {
+	if annotator.dbsnp == nil {
+		return "", nil
+	}
+	matches, err := annotator.dbsnp.FindVariant(variant)
+	if err != nil {
+		return "", err
+	}
+	return strings.Join(data.GetRsids(matches), ";"), nil
+}
+
+// getClinvar 首次调用时读取ClinVar，配置了参考基因组时插入/缺失左对齐，未配置ClinVar时返回nil
+func (annotator *Annotator) getClinvar() (*data.ClinvarDB, error) {
+	annotator.clinvarOnce.Do(func() {
+		clinvarFile := annotator.config.DBFile.Clinvar
+		if clinvarFile == "" {
+			return
+		}
+		reference, err := annotator.getReference()
+		if err != nil {
+			annotator.clinvarErr = err
+			return
+		}
+		clinvar, err := data.ReadClinvarFile(path.Join(annotator.DBPath, clinvarFile), reference)
+		annotator.clinvar, annotator.clinvarErr = &clinvar, err
+	})
+	return annotator.clinvar, annotator.clinvarErr
+}
+
+// SetClinvar 设置SNV的ClinVar信息，无完全一致的记录时统计所在外显子中的致病变异数
+func (annotator *Annotator) SetClinvar(result *snv.Result) error {
+	clinvarDB, err := annotator.getClinvar()
+	if clinvarDB == nil || err != nil {
+		return err
+	}
+	variant := result.GetNormalizedVariant()
+	if clinvar, ok := clinvarDB.GetClinvar(variant); ok {
+		result.Clinvar = &clinvar
+		return nil
+	}
+	refgenes := annotator.GetRefgenes(variant)
+	for _, anno := range result.Annotations {
+		exonOrder, err := strconv.Atoi(strings.TrimPrefix(anno.Exon, "exon"))
+		if err != nil {
+			continue
+		}
+		for _, refgene := range refgenes {
+			if refgene.Transcript != anno.Transcript {
+				continue
+			}
+			if start, end, ok := refgene.GetExonRegion(exonOrder); ok {
+				result.ClinvarExons = append(result.ClinvarExons, data.ClinvarExon{
+					Transcript: anno.Transcript,
+					Exon:       anno.Exon,
+					Pathogenic: clinvarDB.CountPathogenic(refgene.Chrom, start, end),
+				})
+			}
+			break
+		}
+	}
+	return nil
+}
+
+// NewSnvResult 注释单个SNV，包括基因注释及数据库注释；配置了参考基因组时插入/缺失按3'端规则注释，
+// 并以左对齐后的变异匹配数据库。不在panel中的SNV只加载了panel中的转录本，不进行基因注释
+func (annotator *Annotator) NewSnvResult(variant snv.Snv) (result snv.Result, err error) {
+	shifted, err := annotator.ShiftSnv(variant)
+	if err != nil {
+		return
+	}
+	result = snv.Result{Snv: variant, OffPanel: !annotator.IsOnPanel(variant.GetVariant())}
+	if result.OffPanel {
+		result.Annotations = make(snv.Annotations, 0)
+	} else {
+		result.Annotations = annotator.AnnotateSnv(shifted)
+		result.Consequence, result.Impact = result.Annotations.GetMostSevereConsequence()
+	}
+	if reference, _ := annotator.getReference(); reference != nil {
+		normalized := variant.GetVariant()
+		if shifted, ok := shifted.(snv.ShiftedSnv); ok {
+			normalized = shifted.Backward
+		}
+		result.Normalized = &normalized
+	}
+	if result.Frequencies, err = annotator.GetFrequencies(result.GetNormalizedVariant()); err != nil {
+		return
+	}
+	if result.Dbsnp, err = annotator.GetDbsnp(result.GetNormalizedVariant()); err != nil {
+		return
+	}
+	err = annotator.SetClinvar(&result)
+	return
+}
+
+// parallel 使用Threads个协程执行fn(0)...fn(n-1)，返回第一个错误
+func (annotator *Annotator) parallel(n int, fn func(i int) error) error {
+	threads := annotator.Threads
+	if threads > n {
+		threads = n
+	}
+	if threads <= 1 {
+		for i := 0; i < n; i++ {
+			if err := fn(i); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	indexes := make(chan int, threads)
+	errChan := make(chan error, threads)
+	var wg sync.WaitGroup
+	for t := 0; t < threads; t++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				if err := fn(i); err != nil {
+					errChan <- err
+					return
+				}
+			}
+		}()
+	}
+	var err error
+	for i := 0; i < n && err == nil; i++ {
+		select {
+		case indexes <- i:
+		case err = <-errChan:
+		}
+	}
+	close(indexes)
+	wg.Wait()
+	close(errChan)
+	if err != nil {
+		return err
+	}
+	return <-errChan
+}
+
+// AnnotateSnvs 批量注释SNV，结果与输入顺序一致
+func (annotator *Annotator) AnnotateSnvs(snvs snv.Snvs) ([]snv.Result, error) {
+	return annotator.annotateSnvs(snvs, annotator.Phase)
+}
+
+// annotateSnvs 批量注释SNV，phase为true时合并注释同相位的SNV
+func (annotator *Annotator) annotateSnvs(snvs snv.Snvs, phase bool) ([]snv.Result, error) {
+	results := make([]snv.Result, len(snvs))
+	err := annotator.parallel(len(snvs), func(i int) (err error) {
+		results[i], err = annotator.NewSnvResult(snvs[i])
+		return
+	})
+	if err != nil {
+		return nil, err
+	}
+	if phase {
+		annotator.PhaseResults(results)
+	}
+	return results, nil
+}
+
+// PhaseResults 将同一密码子中位于同一单倍型上的SNP(GATK PS/PGT/PID)合并注释，结果添加到各SNP的Phased中；不在panel中的SNP不参与合并
+func (annotator *Annotator) PhaseResults(results []snv.Result) {
+	snvs := make(snv.Snvs, len(results))
+	for i, result := range results {
+		if !result.OffPanel {
+			snvs[i] = result.Snv
+		}
+	}
+	for _, group := range snv.GetPhasedGroups(snvs) {
+		members := make(snv.Snvs, len(group))
+		start, end := -1, -1
+		for i, index := range group {
+			members[i] = snvs[index]
+			variant := snvs[index].GetVariant()
+			if start < 0 || variant.Start < start {
+				start = variant.Start
+			}
+			if variant.End > end {
+				end = variant.End
+			}
+		}
+		refgenes := annotator.index.FindRefgenes(members[0].GetVariant().Chrom, start, end)
+		for _, phasedAnno := range snv.NewPhasedAnnotations(members, refgenes) {
+			for _, index := range group {
+				for _, sn := range phasedAnno.Variants {
+					if sn == snvs[index].GetVariant().GetSn() {
+						results[index].Phased = append(results[index].Phased, phasedAnno)
+						break
+					}
+				}
+			}
+		}
+	}
+}
+
+// AnnotateCnvs 批量注释CNV，结果与输入顺序一致，不在panel中的CNV不进行基因注释
+func (annotator *Annotator) AnnotateCnvs(cnvs cnv.Cnvs) ([]cnv.Result, error) {
+	results := make([]cnv.Result, len(cnvs))
+	err := annotator.parallel(len(cnvs), func(i int) error {
+		results[i] = cnv.Result{Cnv: cnvs[i], OffPanel: !annotator.IsOnPanel(cnvs[i].GetVariant())}
+		if results[i].OffPanel {
+			results[i].Annotations = make(cnv.Annotations, 0)
+			return nil
+		}
+		results[i].Annotations = annotator.AnnotateCnv(cnvs[i])
+		results[i].Consequence, results[i].Impact = results[i].Annotations.GetMostSevereConsequence()
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
@@ -0,0 +1,287 @@
+package annotator
+
+import (
+	"errors"
+	"grandanno/cnv"
+	"grandanno/data"
+	"grandanno/snv"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"strings"
+)
+
+// 输出格式
+const (
+	FormatJSON = "json"
+	FormatVcf  = "vcf"
+	FormatTsv  = "tsv"
+)
+
+// snvBatchSize 流式注释时每个协程每批注释的SNV数
+const snvBatchSize = 256
+
+// Output 输出设置：PerSample为true时SNV注释结果按样本分别输出，否则所有样本输出到一个文件；
+// CompHetReport不为空时输出SNV复合杂合候选报告
+type Output struct {
+	Format        string
+	Columns       []string
+	PerSample     bool
+	CompHetReport string
+}
+
+// NewSnvWriter 根据输出设置创建SNV注释结果输出
+func NewSnvWriter(output Output, outFile string, vcfFile string) (snv.Writer, error) {
+	switch output.Format {
+	case FormatJSON:
+		return snv.NewJSONWriter(outFile)
+	case FormatVcf:
+		header, err := data.ReadVcfHeader(vcfFile)
+		if err != nil {
+			return nil, err
+		}
+		return snv.NewVcfWriter(outFile, header)
+	case FormatTsv:
+		return snv.NewTsvWriter(outFile, output.Columns)
+	default:
+		return nil, errors.New("unknown output format: " + output.Format)
+	}
+}
+
+// NewCnvWriter 根据输出设置创建CNV注释结果输出
+func NewCnvWriter(output Output, outFile string, vcfFile string) (cnv.Writer, error) {
+	switch output.Format {
+	case FormatJSON:
+		return cnv.NewJSONWriter(outFile)
+	case FormatVcf:
+		header, err := data.ReadVcfHeader(vcfFile)
+		if err != nil {
+			return nil, err
+		}
+		return cnv.NewVcfWriter(outFile, header)
+	case FormatTsv:
+		return cnv.NewTsvWriter(outFile, output.Columns)
+	default:
+		return nil, errors.New("unknown output format: " + output.Format)
+	}
+}
+
+// AnnotateGatkVcfFile 流式注释GATK4 Call SNV的VCF结果文件，按注释器的输出设置输出；
+// 创建输出前先检查输入是否按坐标排序，未排序时先进行外部排序
+func (annotator *Annotator) AnnotateGatkVcfFile(vcfFile string, outFile string) error {
+	sorted, err := snv.IsSortedVcfFile(vcfFile)
+	if err != nil {
+		return err
+	}
+	if sorted {
+		return annotator.annotateGatkVcfStream(vcfFile, vcfFile, outFile)
+	}
+	log.Printf("%s is not sorted by coordinate\n", vcfFile)
+	fp, err := ioutil.TempFile("", "grandanno.*.sorted.vcf")
+	if err != nil {
+		return err
+	}
+	sortedFile := fp.Name()
+	fp.Close()
+	defer os.Remove(sortedFile)
+	if err := data.SortVcfFile(vcfFile, sortedFile); err != nil {
+		return err
+	}
+	return annotator.annotateGatkVcfStream(sortedFile, vcfFile, outFile)
+}
+
+// annotateGatkVcfStream 逐条读取已排序的VCF记录进行注释并输出，headerFile为输出VCF表头来源
+func (annotator *Annotator) annotateGatkVcfStream(vcfFile string, headerFile string, outFile string) error {
+	reader, err := snv.NewGatkVcfReader(vcfFile, annotator.Sexes)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+	writers, err := annotator.newGatkSnvWriters(reader.Samples(), outFile, headerFile)
+	if err != nil {
+		return err
+	}
+	log.Printf("start run annotation of snv\n")
+	batchSize := snvBatchSize
+	if annotator.Threads > 1 {
+		batchSize *= annotator.Threads
+	}
+	var collector *snv.CompHetCollector
+	if annotator.Output.CompHetReport != "" {
+		collector = snv.NewCompHetCollector()
+	}
+	batch := make(snv.Snvs, 0, batchSize)
+	linkEnd := 0
+	for eof := false; !eof; {
+		snvs, err := reader.Read()
+		if err == io.EOF {
+			eof = true
+		} else if err != nil {
+			closeSnvWriters(writers)
+			return err
+		}
+		if len(batch) < batchSize && !eof || !eof && annotator.isPhaseLinked(batch, snvs, linkEnd) {
+			linkEnd = annotator.getPhaseLinkEnd(batch, linkEnd, snvs)
+			batch = append(batch, snvs...)
+			continue
+		}
+		if err := annotator.writeGatkSnvs(writers, batch, collector); err != nil {
+			closeSnvWriters(writers)
+			return err
+		}
+		linkEnd = annotator.getPhaseLinkEnd(nil, 0, snvs)
+		batch = append(batch[:0], snvs...)
+	}
+	if err := closeSnvWriters(writers); err != nil {
+		return err
+	}
+	if collector != nil {
+		return snv.WriteCompHetReport(annotator.Output.CompHetReport, collector.GetCandidates())
+	}
+	return nil
+}
+
+// newGatkSnvWriters 创建SNV注释结果输出：按样本输出时每个样本输出一个文件(outFile去掉格式后缀后作为前缀)，否则只输出一个文件
+func (annotator *Annotator) newGatkSnvWriters(samples []string, outFile string, headerFile string) ([]snv.Writer, error) {
+	if !annotator.Output.PerSample {
+		writer, err := NewSnvWriter(annotator.Output, outFile, headerFile)
+		if err != nil {
+			return nil, err
+		}
+		return []snv.Writer{writer}, nil
+	}
+	if len(samples) == 0 {
+		log.Printf("no sample found in %s\n", headerFile)
+	}
+	outPrefix := strings.TrimSuffix(outFile, "."+annotator.Output.Format)
+	writers := make([]snv.Writer, 0, len(samples))
+	for _, sample := range samples {
+		writer, err := NewSnvWriter(annotator.Output, outPrefix+"."+sample+"."+annotator.Output.Format, headerFile)
+		if err != nil {
+			closeSnvWriters(writers)
+			return nil, err
+		}
+		writers = append(writers, writer)
+	}
+	return writers, nil
+}
+
+// writeGatkSnvs 注释一批SNV并输出；按样本输出时每个样本只输出其携带ALT的记录，同相位SNV按样本分别合并；
+// 指定了合子状态时只输出(任一)样本合子状态符合的变异；指定了家系时先标记样本的遗传方式；collector不为nil时收集复合杂合候选；
+// 限制了基因或区域时丢弃不在panel中的变异(MarkOffPanel为false时)，否则标记后不进行基因注释输出
+func (annotator *Annotator) writeGatkSnvs(writers []snv.Writer, batch snv.Snvs, collector *snv.CompHetCollector) error {
+	if annotator.hasPanel() && !annotator.MarkOffPanel {
+		panelSnvs := make(snv.Snvs, 0, len(batch))
+		for _, variant := range batch {
+			if annotator.IsOnPanel(variant.GetVariant()) {
+				panelSnvs = append(panelSnvs, variant)
+			}
+		}
+		batch = panelSnvs
+	}
+	snv.SetInheritance(batch, annotator.Pedigree)
+	results, err := annotator.annotateSnvs(batch, annotator.Phase && !annotator.Output.PerSample)
+	if err != nil {
+		return err
+	}
+	if collector != nil {
+		collector.Add(results)
+	}
+	if !annotator.Output.PerSample {
+		return writeSnvResults(writers[0], snv.FilterResultsByZygosity(results, annotator.Zygosities))
+	}
+	for i, writer := range writers {
+		sampleResults := snv.GetSampleResults(results, i, annotator.Zygosities)
+		if annotator.Phase {
+			annotator.PhaseResults(sampleResults)
+		}
+		if err := writeSnvResults(writer, sampleResults); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeSnvResults 输出SNV注释结果
+func writeSnvResults(writer snv.Writer, results []snv.Result) error {
+	for _, result := range results {
+		if err := writer.Write(result); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// closeSnvWriters 关闭所有SNV注释结果输出，返回第一个错误
+func closeSnvWriters(writers []snv.Writer) (err error) {
+	for _, writer := range writers {
+		if e := writer.Close(); err == nil {
+			err = e
+		}
+	}
+	return
+}
+
+// isPhaseLinked 合并同相位SNV时，下一条记录是否可能与当前批次中的SNV位于同一密码子，linkEnd见getPhaseLinkEnd
+func (annotator *Annotator) isPhaseLinked(batch snv.Snvs, snvs snv.Snvs, linkEnd int) bool {
+	if !annotator.Phase || len(batch) == 0 || len(snvs) == 0 {
+		return false
+	}
+	last, next := batch[len(batch)-1].GetVariant(), snvs[0].GetVariant()
+	return last.Chrom == next.Chrom && next.Start <= linkEnd
+}
+
+// getPhaseLinkEnd 合并同相位SNV时，获取当前批次加入下一条记录后其中SNV所在密码子在基因组上的最大位置(密码子可被内含子分隔)，
+// 位置不超过该值的后续记录不能分到下一批次；batch为加入前的批次，染色体变化时重新计算
+func (annotator *Annotator) getPhaseLinkEnd(batch snv.Snvs, linkEnd int, snvs snv.Snvs) int {
+	if !annotator.Phase || len(snvs) == 0 {
+		return linkEnd
+	}
+	variant := snvs[0].GetVariant()
+	if len(batch) == 0 || batch[len(batch)-1].GetVariant().Chrom != variant.Chrom {
+		linkEnd = 0
+	}
+	if end, ok := snv.GetCodonEnd(variant.Start, annotator.GetRefgenes(variant)); ok && end > linkEnd {
+		linkEnd = end
+	}
+	return linkEnd
+}
+
+// AnnotateXhmmVcfFile 注释XHMM Call CNV的VCF结果文件，每个样本输出一个文件；限制了基因或区域时丢弃不在panel中的CNV(MarkOffPanel为false时)
+func (annotator *Annotator) AnnotateXhmmVcfFile(vcfFile string, outPrefix string) error {
+	xhmmCnvMap, err := cnv.ReadXhmmVcfFile(vcfFile)
+	if err != nil {
+		return err
+	}
+	log.Printf("start run annotation of cnv\n")
+	for sample, cnvs := range xhmmCnvMap {
+		if annotator.hasPanel() && !annotator.MarkOffPanel {
+			panelCnvs := make(cnv.Cnvs, 0, len(cnvs))
+			for _, variant := range cnvs {
+				if annotator.IsOnPanel(variant.GetVariant()) {
+					panelCnvs = append(panelCnvs, variant)
+				}
+			}
+			cnvs = panelCnvs
+		}
+		results, err := annotator.AnnotateCnvs(cnvs)
+		if err != nil {
+			return err
+		}
+		writer, err := NewCnvWriter(annotator.Output, outPrefix+"."+sample+"."+annotator.Output.Format, vcfFile)
+		if err != nil {
+			return err
+		}
+		for _, result := range results {
+			if err := writer.Write(result); err != nil {
+				writer.Close()
+				return err
+			}
+		}
+		if err := writer.Close(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
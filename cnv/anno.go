@@ -3,19 +3,19 @@ package cnv
 import (
 	"fmt"
 	"grandanno/data"
-	"log"
-	"os"
 	"strings"
 )
 
 // Annotation CNV注释
 type Annotation struct {
-	Gene       string `json:"gene"`
-	EntrezID   int    `json:"entrez_id"`
-	Transcript string `json:"transcript"`
-	Region     string `json:"region"`
-	Function   string `json:"function"`
-	Exons      []int  `json:"exons"`
+	Gene         string   `json:"gene"`
+	EntrezID     int      `json:"entrez_id"`
+	Transcript   string   `json:"transcript"`
+	Region       string   `json:"region"`
+	Function     string   `json:"function"`
+	Exons        []int    `json:"exons"`
+	Consequences []string `json:"consequences"`
+	Impact       string   `json:"impact"`
 }
 
 // AddExon 新增Exon信息
@@ -58,8 +58,10 @@ func (annos Annotations) IsSpecial() bool {
 }
 
 // AnnoIntergeic 注释基因间区
-func (annos *Annotations) AnnoIntergeic() {
-	*annos = append(*annos, Annotation{Region: "intergenic"})
+func (annos *Annotations) AnnoIntergeic(cnv Cnv) {
+	anno := Annotation{Region: "intergenic"}
+	anno.SetConsequences(cnv, data.Refgene{})
+	*annos = append(*annos, anno)
 }
 
 // AnnoStream 注释上下游区
@@ -67,12 +69,14 @@ func (annos *Annotations) AnnoStream(cnv Cnv, refgenes data.Refgenes) {
 	for _, refgene := range refgenes {
 		for _, region := range refgene.Streams {
 			if cnv.GetVariant().Start <= region.End && cnv.GetVariant().End >= region.Start {
-				*annos = append(*annos, Annotation{
+				anno := Annotation{
 					Gene:       refgene.Gene,
 					EntrezID:   refgene.EntrezID,
 					Transcript: refgene.Transcript,
 					Region:     region.Typo,
-				})
+				}
+				anno.SetConsequences(cnv, refgene)
+				*annos = append(*annos, anno)
 				break
 			}
 		}
@@ -98,6 +102,7 @@ func (annos *Annotations) AnnoGene(cnv Cnv, refgenes data.Refgenes) {
 			}
 			if refgene.Tag == "unk" {
 				anno.Region = "unkCDS"
+				anno.SetConsequences(cnv, refgene)
 				unkAnnos = append(unkAnnos, anno)
 			} else {
 				for _, region := range refgene.Regions {
@@ -109,9 +114,11 @@ func (annos *Annotations) AnnoGene(cnv Cnv, refgenes data.Refgenes) {
 					}
 				}
 				if refgene.Tag == "cmpl" {
+					anno.SetConsequences(cnv, refgene)
 					cmplAnnos = append(cmplAnnos, anno)
 				} else {
 					anno.Region = "incmplCDS"
+					anno.SetConsequences(cnv, refgene)
 					incmplAnnos = append(incmplAnnos, anno)
 				}
 			}
@@ -126,43 +133,24 @@ func (annos *Annotations) AnnoGene(cnv Cnv, refgenes data.Refgenes) {
 	}
 }
 
-// RunAnnotation 运行注释
-func RunAnnotation(cnvs Cnvs, refgeneMap map[string]data.Refgene, refidxs data.Refidxs, outJSONFile string) {
-	fp, err := os.Create(outJSONFile)
-	if err != nil {
-		log.Fatal(err)
+// Result CNV及其注释结果，OffPanel为true时不在panel中且Annotations为空
+type Result struct {
+	Cnv         Cnv         `json:"cnv"`
+	Annotations Annotations `json:"annotations"`
+	Consequence string      `json:"most_severe_consequence,omitempty"`
+	Impact      string      `json:"impact,omitempty"`
+	OffPanel    bool        `json:"off_panel,omitempty"`
+}
+
+// NewAnnotations 注释CNV：依次注释基因区、上下游区和基因间区
+func NewAnnotations(cnv Cnv, refgenes data.Refgenes) Annotations {
+	annos := make(Annotations, 0)
+	annos.AnnoGene(cnv, refgenes)
+	if len(annos) == 0 {
+		annos.AnnoStream(cnv, refgenes)
 	}
-	defer fp.Close()
-	for i := 0; i < len(cnvs); i++ {
-		cnvPos1, cnvPos2 := cnvs[i].GetVariant().GetNumericalPosition()
-		for j := 0; j < len(refidxs); j++ {
-			refPos1, refPos2 := refidxs[j].GetNumericalPosition()
-			if cnvPos2 < refPos1 {
-				break
-			} else if cnvPos1 > refPos2 {
-				continue
-			} else {
-				annos := make(Annotations, 0)
-				if cnvPos2 < refPos1 {
-					annos.AnnoIntergeic()
-				} else {
-					refgenes := refidxs[j].GetRefgenes(refgeneMap)
-					annos.AnnoGene(cnvs[i], refgenes)
-					if len(annos) == 0 {
-						annos.AnnoStream(cnvs[i], refgenes)
-					}
-					if len(annos) == 0 {
-						annos.AnnoIntergeic()
-					}
-				}
-				json, err := data.ConvertToJSON(map[string]interface{}{"snv": cnvs[i], "annotations": annos})
-				if err != nil {
-					log.Fatal(err)
-				}
-				if _, err := fp.WriteString(json + "\n"); err != nil {
-					log.Fatal(err)
-				}
-			}
-		}
+	if len(annos) == 0 {
+		annos.AnnoIntergeic(cnv)
 	}
+	return annos
 }
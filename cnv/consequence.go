@@ -0,0 +1,77 @@
+package cnv
+
+import "grandanno/data"
+
+// SetConsequences 将CNV注释映射为Sequence Ontology consequence terms及影响程度：
+// 覆盖整个转录本时为transcript_ablation/transcript_amplification，否则按重叠的区域元件注释；
+// 与转录本的区域元件重叠时另外添加feature_truncation/feature_elongation
+func (anno *Annotation) SetConsequences(cnv Cnv, refgene data.Refgene) {
+	variant := cnv.GetVariant()
+	isDel := cnv.GetType() == "DEL"
+	var terms []string
+	overlaps := false
+	for _, region := range refgene.Regions {
+		if variant.Start <= region.End && variant.End >= region.Start {
+			overlaps = true
+			break
+		}
+	}
+	switch anno.Region {
+	case "intergenic":
+		terms = append(terms, "intergenic_variant")
+	case "upstream":
+		terms = append(terms, "upstream_gene_variant")
+	case "downstream":
+		terms = append(terms, "downstream_gene_variant")
+	case "unkCDS":
+		terms = append(terms, "non_coding_transcript_variant")
+	default:
+		if variant.Start <= refgene.ExonStart && variant.End >= refgene.ExonEnd {
+			if isDel {
+				terms = append(terms, "transcript_ablation")
+			} else {
+				terms = append(terms, "transcript_amplification")
+			}
+			break
+		}
+		for _, region := range refgene.Regions {
+			if variant.Start > region.End || variant.End < region.Start {
+				continue
+			}
+			switch region.Typo {
+			case "cds":
+				if isDel {
+					terms = append(terms, "exon_loss_variant")
+				} else {
+					terms = append(terms, "coding_sequence_variant")
+				}
+			case "utr5":
+				terms = append(terms, "5_prime_UTR_variant")
+			case "utr3":
+				terms = append(terms, "3_prime_UTR_variant")
+			case "intron":
+				terms = append(terms, "intron_variant")
+			}
+		}
+	}
+	if overlaps {
+		if isDel {
+			terms = append(terms, "feature_truncation")
+		} else {
+			terms = append(terms, "feature_elongation")
+		}
+	}
+	anno.Consequences = data.SortConsequences(terms)
+	if len(anno.Consequences) > 0 {
+		anno.Impact = data.GetConsequenceImpact(anno.Consequences[0])
+	}
+}
+
+// GetMostSevereConsequence 获取所有注释中最严重的consequence term及其影响程度
+func (annos Annotations) GetMostSevereConsequence() (string, string) {
+	var terms []string
+	for _, anno := range annos {
+		terms = append(terms, anno.Consequences...)
+	}
+	return data.GetMostSevereConsequence(terms)
+}
@@ -0,0 +1,238 @@
+package cnv
+
+import (
+	"bufio"
+	"errors"
+	"grandanno/data"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Writer CNV注释结果输出接口
+type Writer interface {
+	Write(result Result) error
+	Close() error
+}
+
+// JSONWriter 注释结果逐行输出为JSON
+type JSONWriter struct {
+	fp     *os.File
+	writer *bufio.Writer
+}
+
+// NewJSONWriter 创建JSON输出
+func NewJSONWriter(outFile string) (*JSONWriter, error) {
+	fp, err := os.Create(outFile)
+	if err != nil {
+		return nil, err
+	}
+	return &JSONWriter{fp: fp, writer: bufio.NewWriter(fp)}, nil
+}
+
+// Write 输出一条注释结果
+func (writer *JSONWriter) Write(result Result) error {
+	json, err := data.ConvertToJSON(result)
+	if err != nil {
+		return err
+	}
+	_, err = writer.writer.WriteString(json)
+	return err
+}
+
+// Close 关闭输出文件
+func (writer *JSONWriter) Close() error {
+	if err := writer.writer.Flush(); err != nil {
+		writer.fp.Close()
+		return err
+	}
+	return writer.fp.Close()
+}
+
+// VcfWriter 注释结果输出为VCF，注释信息写入INFO字段
+type VcfWriter struct {
+	fp     *os.File
+	writer *bufio.Writer
+}
+
+// NewVcfWriter 创建VCF输出，header为原始VCF表头
+func NewVcfWriter(outFile string, header []string) (*VcfWriter, error) {
+	fp, err := os.Create(outFile)
+	if err != nil {
+		return nil, err
+	}
+	writer := &VcfWriter{fp: fp, writer: bufio.NewWriter(fp)}
+	for _, line := range data.InsertVcfHeader(header, data.GetVcfAnnoHeader(), data.GetVcfOffPanelHeader()) {
+		if _, err := writer.writer.WriteString(line + "\n"); err != nil {
+			fp.Close()
+			return nil, err
+		}
+	}
+	return writer, nil
+}
+
+// GetVcfAnno 获取VCF INFO中的注释信息
+func (anno Annotation) GetVcfAnno(allele string) string {
+	var entrezID, exon string
+	if anno.EntrezID > 0 {
+		entrezID = strconv.Itoa(anno.EntrezID)
+	}
+	if len(anno.Exons) > 0 {
+		exon = anno.GetCds()
+	}
+	values := []string{
+		allele, anno.Gene, entrezID, anno.Transcript, exon, "", "", anno.Region, anno.Function,
+		data.JoinConsequences(anno.Consequences), anno.Impact,
+	}
+	for i, value := range values {
+		values[i] = data.EscapeVcfInfo(value)
+	}
+	return strings.Join(values, "|")
+}
+
+// Write 输出一条注释结果
+func (writer *VcfWriter) Write(result Result) error {
+	xhmmCnv, ok := result.Cnv.(XhmmCnv)
+	if !ok {
+		return errors.New("vcf output only supports xhmm cnv")
+	}
+	annos := make([]string, len(result.Annotations))
+	for i, anno := range result.Annotations {
+		annos[i] = anno.GetVcfAnno(xhmmCnv.Variant.Alt.String())
+	}
+	line := xhmmCnv.VcfLine
+	if len(annos) > 0 {
+		line = data.AddVcfInfo(line, data.VcfAnnoKey, strings.Join(annos, ","))
+	}
+	if result.OffPanel {
+		line = data.AddVcfFlag(line, data.VcfOffPanelKey)
+	}
+	_, err := writer.writer.WriteString(line + "\n")
+	return err
+}
+
+// Close 关闭输出文件
+func (writer *VcfWriter) Close() error {
+	err := writer.writer.Flush()
+	if e := writer.fp.Close(); err == nil {
+		err = e
+	}
+	return err
+}
+
+// TsvColumns TSV默认输出列
+var TsvColumns = []string{
+	"chrom", "start", "end", "ref", "alt", "depth",
+	"gene", "entrez_id", "transcript", "exon", "region", "function", "consequence", "impact", "off_panel",
+}
+
+// getTsvValue 获取TSV列的值，CNV不适用的列输出"."
+func getTsvValue(column string, result Result, anno Annotation) (value string, ok bool) {
+	variant := result.Cnv.GetVariant()
+	xhmmCnv, isXhmm := result.Cnv.(XhmmCnv)
+	switch column {
+	case "chrom":
+		value = variant.Chrom
+	case "start":
+		value = strconv.Itoa(variant.Start)
+	case "end":
+		value = strconv.Itoa(variant.End)
+	case "ref":
+		value = variant.Ref.String()
+	case "alt":
+		value = variant.Alt.String()
+	case "depth":
+		if isXhmm {
+			value = strconv.FormatFloat(xhmmCnv.Information.MeanReadDepth, 'f', -1, 64)
+		}
+	case "qual", "filter", "ratio", "na_change", "aa_change", "normalized", "phased_na_change", "phased_aa_change", "phased_function":
+	case "gene":
+		value = anno.Gene
+	case "entrez_id":
+		if anno.EntrezID > 0 {
+			value = strconv.Itoa(anno.EntrezID)
+		}
+	case "transcript":
+		value = anno.Transcript
+	case "exon":
+		if len(anno.Exons) > 0 {
+			value = anno.GetCds()
+		}
+	case "region":
+		value = anno.Region
+	case "function":
+		value = anno.Function
+	case "consequence":
+		value = data.JoinConsequences(anno.Consequences)
+	case "impact":
+		value = anno.Impact
+	case "most_severe_consequence":
+		value = result.Consequence
+	case "off_panel":
+		if result.OffPanel {
+			value = "yes"
+		}
+	default:
+		return "", false
+	}
+	if value == "" {
+		value = "."
+	}
+	return value, true
+}
+
+// TsvWriter 注释结果输出为TSV，每个变异的每个转录本注释输出一行
+type TsvWriter struct {
+	fp      *os.File
+	writer  *bufio.Writer
+	columns []string
+}
+
+// NewTsvWriter 创建TSV输出，columns为空时输出默认列
+func NewTsvWriter(outFile string, columns []string) (*TsvWriter, error) {
+	if len(columns) == 0 {
+		columns = TsvColumns
+	}
+	for _, column := range columns {
+		if _, ok := getTsvValue(column, Result{Cnv: XhmmCnv{}}, Annotation{}); !ok {
+			return nil, errors.New("unknown tsv column: " + column)
+		}
+	}
+	fp, err := os.Create(outFile)
+	if err != nil {
+		return nil, err
+	}
+	writer := &TsvWriter{fp: fp, writer: bufio.NewWriter(fp), columns: columns}
+	if _, err := writer.writer.WriteString("#" + strings.Join(columns, "\t") + "\n"); err != nil {
+		fp.Close()
+		return nil, err
+	}
+	return writer, nil
+}
+
+// Write 输出一条注释结果
+func (writer *TsvWriter) Write(result Result) error {
+	values := make([]string, len(writer.columns))
+	annos := result.Annotations
+	if len(annos) == 0 {
+		annos = Annotations{Annotation{}}
+	}
+	for _, anno := range annos {
+		for i, column := range writer.columns {
+			values[i], _ = getTsvValue(column, result, anno)
+		}
+		if _, err := writer.writer.WriteString(strings.Join(values, "\t") + "\n"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close 关闭输出文件
+func (writer *TsvWriter) Close() error {
+	err := writer.writer.Flush()
+	if e := writer.fp.Close(); err == nil {
+		err = e
+	}
+	return err
+}
@@ -4,7 +4,7 @@ import (
 	"bytes"
 	"grandanno/data"
 	"log"
-	"os"
+	"sort"
 	"strconv"
 	"strings"
 )
@@ -17,6 +17,7 @@ type XhmmCnv struct {
 		MeanOriginalReadDepth float64 `json:"mean_original_depth"`
 	} `json:"information"`
 	OtherInfo []string `json:"other_info"`
+	VcfLine   string   `json:"-"`
 }
 
 // GetVariant 获取变异信息
@@ -31,9 +32,10 @@ func (cnv XhmmCnv) GetType() string {
 
 // InitXhmmCnv 获取初始化XHMM CNV
 func InitXhmmCnv(head []string, vcfLine string) (xhmmCnvMap map[string]Cnv, err error) {
+	xhmmCnvMap = make(map[string]Cnv)
 	field := strings.Split(vcfLine, "\t")
 	tmp1 := strings.Split(field[2], ":")
-	chrom := tmp1[0] // important
+	chrom := data.ConvertChrom(tmp1[0]) // important
 	tmp2 := strings.Split(tmp1[1], "-")
 	pos, err := data.Strs2Ints([]string{tmp2[0], tmp2[1]})
 	if err != nil {
@@ -68,6 +70,7 @@ func InitXhmmCnv(head []string, vcfLine string) (xhmmCnvMap map[string]Cnv, err
 				Alt:   data.Sequence(alts[genotype-1]),
 			},
 			OtherInfo: otherInfos,
+			VcfLine:   vcfLine,
 		}
 		xhmmCnv.Information.MeanReadDepth = mrd
 		xhmmCnv.Information.MeanOriginalReadDepth = mord
@@ -76,20 +79,26 @@ func InitXhmmCnv(head []string, vcfLine string) (xhmmCnvMap map[string]Cnv, err
 	return
 }
 
+// getXhmmChrom 获取XHMM VCF行的染色体(ID列格式为chrom:start-end)
+func getXhmmChrom(vcfLine string) string {
+	field := strings.SplitN(vcfLine, "\t", 4)
+	if len(field) < 3 {
+		return field[0]
+	}
+	return strings.SplitN(field[2], ":", 2)[0]
+}
+
 // ReadXhmmVcfFile 读取XHMM VCF文件
-func ReadXhmmVcfFile(vcfFile string, xhmmCnvMapChan chan map[string]Cnvs) {
+func ReadXhmmVcfFile(vcfFile string) (xhmmCnvMap map[string]Cnvs, err error) {
 	log.Printf("start read %s\n", vcfFile)
-	xhmmCnvMap := make(map[string]Cnvs, 0)
+	xhmmCnvMap = make(map[string]Cnvs, 0)
 	var head []string
-	fp, err := os.Open(vcfFile)
-	if err != nil {
-		log.Fatal(err)
-	}
-	defer fp.Close()
 	lines, err := data.ReadFile(vcfFile)
 	if err != nil {
-		log.Fatal(err)
+		return
 	}
+	unknown := make(data.UnknownChroms)
+	defer unknown.Report(vcfFile)
 	for _, line := range lines {
 		line = bytes.TrimSpace(line)
 		if len(line) == 0 {
@@ -99,10 +108,12 @@ func ReadXhmmVcfFile(vcfFile string, xhmmCnvMapChan chan map[string]Cnvs) {
 			if bytes.HasPrefix(line, []byte("#CHROM")) {
 				head = strings.Split(string(line), "\t")[9:]
 			}
+		} else if chrom := getXhmmChrom(string(line)); !data.IsKnownChrom(chrom) {
+			unknown[chrom]++
 		} else {
-			cnvMap, err := InitXhmmCnv(head, string(line))
-			if err != nil {
-				log.Fatal(err)
+			var cnvMap map[string]Cnv
+			if cnvMap, err = InitXhmmCnv(head, string(line)); err != nil {
+				return
 			}
 			for sample, cnv := range cnvMap {
 				if cnvs, ok := xhmmCnvMap[sample]; ok {
@@ -113,4 +124,8 @@ func ReadXhmmVcfFile(vcfFile string, xhmmCnvMapChan chan map[string]Cnvs) {
 			}
 		}
 	}
+	for _, cnvs := range xhmmCnvMap {
+		sort.Sort(cnvs)
+	}
+	return
 }
@@ -0,0 +1,241 @@
+package data
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/gob"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path"
+)
+
+// AnnoDBVersion 二进制注释数据库版本，数据结构变化时需递增
+const AnnoDBVersion = 2
+
+// annoDBMagic 二进制注释数据库文件标识
+const annoDBMagic = "GRANDANNO-DB"
+
+// AnnoDBSource 生成数据库所用的源文件，ModTime为修改时间(Unix纳秒)
+type AnnoDBSource struct {
+	Name    string `json:"name"`
+	Size    int64  `json:"size"`
+	ModTime int64  `json:"mtime,omitempty"`
+	SHA256  string `json:"sha256"`
+}
+
+// NewAnnoDBSource 计算源文件大小、修改时间及SHA-256
+func NewAnnoDBSource(file string) (source AnnoDBSource, err error) {
+	fp, err := os.Open(file)
+	if err != nil {
+		return
+	}
+	defer fp.Close()
+	info, err := fp.Stat()
+	if err != nil {
+		return
+	}
+	hash := sha256.New()
+	size, err := io.Copy(hash, fp)
+	if err != nil {
+		return
+	}
+	return AnnoDBSource{
+		Name:    path.Base(file),
+		Size:    size,
+		ModTime: info.ModTime().UnixNano(),
+		SHA256:  hex.EncodeToString(hash.Sum(nil)),
+	}, nil
+}
+
+// Check 检查dbPath中的源文件是否与生成时一致：文件不存在或大小不同时报错；hash为true时再比较SHA-256，
+// 否则只比较修改时间，不同时输出提示(如复制后的数据库目录)，不读取文件内容
+func (source AnnoDBSource) Check(dbPath string, hash bool) error {
+	file := path.Join(dbPath, source.Name)
+	info, err := os.Stat(file)
+	if os.IsNotExist(err) {
+		return fmt.Errorf("%s is missing, please rerun pre", source.Name)
+	} else if err != nil {
+		return err
+	}
+	if info.Size() != source.Size {
+		return fmt.Errorf("%s has changed since the database was built, please rerun pre", source.Name)
+	}
+	if !hash {
+		if info.ModTime().UnixNano() != source.ModTime {
+			log.Printf("%s was modified after the database was built, run validate to check it\n", source.Name)
+		}
+		return nil
+	}
+	actual, err := NewAnnoDBSource(file)
+	if err != nil {
+		return err
+	}
+	if actual.SHA256 != source.SHA256 {
+		return fmt.Errorf("%s has changed since the database was built, please rerun pre", source.Name)
+	}
+	return nil
+}
+
+// AnnoDBHeader 二进制注释数据库文件头
+type AnnoDBHeader struct {
+	Magic        string
+	Version      int
+	Build        string
+	UpDownStream int
+	Sources      []AnnoDBSource
+}
+
+// annoDBTree 区间树的存储形式：转录本在Refgenes中的下标及子树最大终止位置
+type annoDBTree struct {
+	Refgenes []int
+	MaxEnds  []int
+}
+
+// AnnoDB 二进制注释数据库：已设置Entrez ID、cDNA及蛋白序列的转录本及其区间树索引
+type AnnoDB struct {
+	Header   AnnoDBHeader
+	Refgenes Refgenes
+	Index    RefgeneIndex
+}
+
+// NewAnnoDB 创建注释数据库，sourceFiles为生成数据库所用的源文件
+func NewAnnoDB(refgenes Refgenes, sourceFiles []string) (db AnnoDB, err error) {
+	db = AnnoDB{
+		Header: AnnoDBHeader{
+			Magic:        annoDBMagic,
+			Version:      AnnoDBVersion,
+			Build:        Config.Build,
+			UpDownStream: Config.Param.UpDownStream,
+		},
+		Refgenes: refgenes,
+		Index:    NewRefgeneIndex(refgenes),
+	}
+	for _, file := range sourceFiles {
+		var source AnnoDBSource
+		if source, err = NewAnnoDBSource(file); err != nil {
+			return
+		}
+		db.Header.Sources = append(db.Header.Sources, source)
+	}
+	return
+}
+
+// WriteAnnoDBFile 输出二进制注释数据库文件
+func WriteAnnoDBFile(dbFile string, db AnnoDB) error {
+	log.Printf("start write %s\n", dbFile)
+	positions := make(map[string]int, len(db.Refgenes))
+	for i, refgene := range db.Refgenes {
+		positions[refgene.GetSn()] = i
+	}
+	trees := make(map[string]annoDBTree, len(db.Index))
+	for chrom, tree := range db.Index {
+		dbTree := annoDBTree{Refgenes: make([]int, len(tree.refgenes)), MaxEnds: tree.maxEnds}
+		for i, refgene := range tree.refgenes {
+			dbTree.Refgenes[i] = positions[refgene.GetSn()]
+		}
+		trees[chrom] = dbTree
+	}
+	fp, err := os.Create(dbFile)
+	if err != nil {
+		return err
+	}
+	defer fp.Close()
+	writer := bufio.NewWriter(fp)
+	encoder := gob.NewEncoder(writer)
+	if err := encoder.Encode(db.Header); err != nil {
+		return err
+	}
+	if err := encoder.Encode(db.Refgenes); err != nil {
+		return err
+	}
+	if err := encoder.Encode(trees); err != nil {
+		return err
+	}
+	return writer.Flush()
+}
+
+// readAnnoDBHeader 读取并检查数据库文件头
+func readAnnoDBHeader(decoder *gob.Decoder) (header AnnoDBHeader, err error) {
+	if err = decoder.Decode(&header); err != nil {
+		return
+	}
+	if header.Magic != annoDBMagic {
+		err = errors.New("not a grandanno database")
+	} else if header.Version != AnnoDBVersion {
+		err = fmt.Errorf("database version %d is not supported (expect %d), please rerun pre", header.Version, AnnoDBVersion)
+	}
+	return
+}
+
+// ReadAnnoDBHeader 只读取二进制注释数据库的文件头
+func ReadAnnoDBHeader(dbFile string) (AnnoDBHeader, error) {
+	fp, err := os.Open(dbFile)
+	if err != nil {
+		return AnnoDBHeader{}, err
+	}
+	defer fp.Close()
+	return readAnnoDBHeader(gob.NewDecoder(bufio.NewReader(fp)))
+}
+
+// ReadAnnoDBFile 读取二进制注释数据库文件
+func ReadAnnoDBFile(dbFile string) (db AnnoDB, err error) {
+	log.Printf("start read %s\n", dbFile)
+	fp, err := os.Open(dbFile)
+	if err != nil {
+		return
+	}
+	defer fp.Close()
+	decoder := gob.NewDecoder(bufio.NewReader(fp))
+	if db.Header, err = readAnnoDBHeader(decoder); err != nil {
+		return
+	}
+	if err = decoder.Decode(&db.Refgenes); err != nil {
+		return
+	}
+	var trees map[string]annoDBTree
+	if err = decoder.Decode(&trees); err != nil {
+		return
+	}
+	db.Index = make(RefgeneIndex, len(trees))
+	for chrom, dbTree := range trees {
+		if len(dbTree.Refgenes) != len(dbTree.MaxEnds) {
+			return db, errors.New("broken refgene index of " + chrom)
+		}
+		tree := &refgeneTree{
+			refgenes: make(Refgenes, len(dbTree.Refgenes)),
+			starts:   make([]int, len(dbTree.Refgenes)),
+			ends:     make([]int, len(dbTree.Refgenes)),
+			maxEnds:  dbTree.MaxEnds,
+		}
+		for i, index := range dbTree.Refgenes {
+			if index < 0 || index >= len(db.Refgenes) {
+				return db, errors.New("broken refgene index of " + chrom)
+			}
+			tree.refgenes[i] = db.Refgenes[index]
+			tree.starts[i], tree.ends[i] = tree.refgenes[i].GetSpan()
+		}
+		db.Index[chrom] = tree
+	}
+	return
+}
+
+// Check 检查数据库与当前配置是否一致，dbPath中的源文件需与生成时一致(见AnnoDBSource.Check)，
+// 注释时只比较大小及修改时间，validate时hash为true
+func (header AnnoDBHeader) Check(dbPath string, hash bool) error {
+	if header.Build != Config.Build {
+		return fmt.Errorf("database build %q does not match config build %q", header.Build, Config.Build)
+	}
+	if header.UpDownStream != Config.Param.UpDownStream {
+		return fmt.Errorf("database up_down_stream %d does not match config %d", header.UpDownStream, Config.Param.UpDownStream)
+	}
+	for _, source := range header.Sources {
+		if err := source.Check(dbPath, hash); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,182 @@
+package data
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// chromAliases 染色体名称及别名到规范名称(配置文件中的name)的映射
+var chromAliases = make(map[string]string)
+
+// chromNames 规范名称到所有名称(包括规范名称及别名)的映射
+var chromNames = make(map[string][]string)
+
+// extraChroms 不在当前基因组版本中的染色体的排序，排在配置的染色体之后
+var extraChroms = struct {
+	sync.Mutex
+	orders map[string]int
+}{orders: make(map[string]int)}
+
+// getExtraChromOrder 获取不在当前基因组版本中的染色体的排序，首次出现时分配
+func getExtraChromOrder(name string) int {
+	extraChroms.Lock()
+	defer extraChroms.Unlock()
+	order, ok := extraChroms.orders[name]
+	if !ok {
+		order = len(Config.Chrom) + len(extraChroms.orders) + 1
+		extraChroms.orders[name] = order
+	}
+	return order
+}
+
+// isMitochondrionName 名称是否为线粒体的常用名称
+func isMitochondrionName(name string) bool {
+	name = strings.TrimPrefix(name, "chr")
+	return name == "M" || name == "MT"
+}
+
+// getDefaultAliases 获取染色体的默认别名：增减chr前缀，线粒体兼容M/MT/chrM/chrMT
+func getDefaultAliases(name string) []string {
+	if isMitochondrionName(name) {
+		return []string{"M", "MT", "chrM", "chrMT"}
+	}
+	if strings.HasPrefix(name, "chr") {
+		return []string{strings.TrimPrefix(name, "chr")}
+	}
+	return []string{"chr" + name}
+}
+
+// AddChromAlias 添加染色体别名，别名已存在时忽略
+func AddChromAlias(alias string, chrom string) {
+	if _, ok := chromAliases[alias]; !ok {
+		chromAliases[alias] = chrom
+		chromNames[chrom] = append(chromNames[chrom], alias)
+	}
+}
+
+// InitChromAliases 根据当前基因组版本的染色体列表初始化别名映射，配置的别名优先于默认别名
+func InitChromAliases() {
+	chromAliases = make(map[string]string)
+	chromNames = make(map[string][]string)
+	extraChroms.Lock()
+	extraChroms.orders = make(map[string]int)
+	extraChroms.Unlock()
+	for _, chrom := range Config.Chrom {
+		AddChromAlias(chrom.Name, chrom.Name)
+		for _, alias := range chrom.Aliases {
+			AddChromAlias(alias, chrom.Name)
+		}
+	}
+	for _, chrom := range Config.Chrom {
+		for _, alias := range getDefaultAliases(chrom.Name) {
+			AddChromAlias(alias, chrom.Name)
+		}
+	}
+}
+
+// ReadChromAliasFile 读取染色体别名文件(UCSC chromAlias格式)：
+// 以#开头的表头时每行为同一条序列在不同命名体系中的名称，否则每行为"别名\t名称\t来源"；
+// 行中任一名称属于当前基因组版本时，其余名称作为该染色体的别名
+func ReadChromAliasFile(aliasFile string) error {
+	log.Printf("start read %s\n", aliasFile)
+	fp, err := OpenFile(aliasFile)
+	if err != nil {
+		return err
+	}
+	defer fp.Close()
+	reader := bufio.NewReader(fp)
+	multiColumn := false
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return err
+		}
+		eof := err == io.EOF
+		line = strings.TrimRight(line, "\r\n")
+		if strings.HasPrefix(line, "#") {
+			multiColumn = true
+		} else if line != "" {
+			names := strings.Split(line, "\t")
+			if !multiColumn && len(names) > 2 {
+				names = names[:2]
+			}
+			for _, name := range names {
+				if chrom, ok := GetChromName(name); ok {
+					for _, alias := range names {
+						if alias != "" {
+							AddChromAlias(alias, chrom)
+						}
+					}
+					break
+				}
+			}
+		}
+		if eof {
+			return nil
+		}
+	}
+}
+
+// GetChromAliases 获取染色体的所有名称，规范名称在前
+func GetChromAliases(name string) []string {
+	if chrom, ok := chromAliases[name]; ok {
+		return chromNames[chrom]
+	}
+	return []string{name}
+}
+
+// GetChromName 获取染色体在当前基因组版本中的规范名称
+func GetChromName(name string) (string, bool) {
+	chrom, ok := chromAliases[name]
+	return chrom, ok
+}
+
+// IsKnownChrom 染色体是否属于当前基因组版本
+func IsKnownChrom(name string) bool {
+	_, ok := chromAliases[name]
+	return ok
+}
+
+// ConvertChrom 转为染色体规范名称，不在当前基因组版本中的染色体保持原名
+func ConvertChrom(name string) string {
+	if chrom, ok := chromAliases[name]; ok {
+		return chrom
+	}
+	return name
+}
+
+// IsMitochondrion 是否为线粒体
+func IsMitochondrion(chrom string) bool {
+	return isMitochondrionName(ConvertChrom(chrom))
+}
+
+// UnknownChroms 读取文件时遇到的不在当前基因组版本中的染色体及被跳过的记录数
+type UnknownChroms map[string]int
+
+// Report 输出被跳过的染色体汇总
+func (unknown UnknownChroms) Report(source string) {
+	if len(unknown) == 0 {
+		return
+	}
+	var chroms []string
+	total := 0
+	for chrom, count := range unknown {
+		chroms = append(chroms, chrom)
+		total += count
+	}
+	sort.Strings(chroms)
+	if len(chroms) > 10 {
+		chroms = append(chroms[:10], "...")
+	}
+	for i, chrom := range chroms {
+		if count, ok := unknown[chrom]; ok {
+			chroms[i] = fmt.Sprintf("%s(%d)", chrom, count)
+		}
+	}
+	log.Printf("skip %d records of %s on unknown contigs: %s\n", total, source, strings.Join(chroms, ","))
+}
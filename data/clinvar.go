@@ -0,0 +1,131 @@
+package data
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"log"
+	"sort"
+	"strings"
+)
+
+// Clinvar ClinVar临床意义
+type Clinvar struct {
+	VariationID  string   `json:"variation_id"`
+	Significance string   `json:"significance"`
+	ReviewStatus string   `json:"review_status"`
+	Diseases     []string `json:"diseases"`
+}
+
+// IsPathogenic 是否为致病或可能致病变异
+func (clinvar Clinvar) IsPathogenic() bool {
+	for _, sig := range strings.FieldsFunc(strings.ToLower(clinvar.Significance), func(r rune) bool {
+		return r == '/' || r == '|' || r == ','
+	}) {
+		if sig == "pathogenic" || sig == "likely pathogenic" {
+			return true
+		}
+	}
+	return false
+}
+
+// ClinvarExon 与查询变异位于同一转录本外显子中的ClinVar致病变异数
+type ClinvarExon struct {
+	Transcript string `json:"transcript"`
+	Exon       string `json:"exon"`
+	Pathogenic int    `json:"pathogenic"`
+}
+
+// ClinvarDB ClinVar数据库：以标准化变异编号为Key，并记录致病变异位置
+type ClinvarDB struct {
+	Variants    map[string]Clinvar
+	Pathogenics map[string][]int
+}
+
+// getClinvarValue 获取ClinVar INFO值，下划线还原为空格
+func getClinvarValue(record VcfRecord, key string) string {
+	value, _ := record.GetInfo(key)
+	return strings.Replace(value, "_", " ", -1)
+}
+
+// addRecord 添加ClinVar VCF记录，reference不为空时插入/缺失左对齐后作为Key，与人群频率及dbSNP的匹配方式一致
+func (db ClinvarDB) addRecord(record VcfRecord, reference *Faidx) error {
+	clinvar := Clinvar{
+		VariationID:  record.ID,
+		Significance: getClinvarValue(record, "CLNSIG"),
+		ReviewStatus: getClinvarValue(record, "CLNREVSTAT"),
+	}
+	if diseases := getClinvarValue(record, "CLNDN"); diseases != "" {
+		clinvar.Diseases = strings.Split(diseases, "|")
+	}
+	for i, alt := range record.Alts {
+		if alt == "." || alt == "*" || strings.HasPrefix(alt, "<") {
+			continue
+		}
+		variant, err := record.GetVariant(i).LeftAlign(reference)
+		if err != nil {
+			return err
+		}
+		db.Variants[variant.GetSn()] = clinvar
+		if clinvar.IsPathogenic() {
+			db.Pathogenics[variant.Chrom] = append(db.Pathogenics[variant.Chrom], variant.Start)
+		}
+	}
+	return nil
+}
+
+// ReadClinvarFile 读取ClinVar VCF文件，reference为空时不对插入/缺失左对齐
+func ReadClinvarFile(clinvarFile string, reference *Faidx) (db ClinvarDB, err error) {
+	log.Printf("start read %s\n", clinvarFile)
+	db = ClinvarDB{Variants: make(map[string]Clinvar), Pathogenics: make(map[string][]int)}
+	fp, err := OpenFile(clinvarFile)
+	if err != nil {
+		return
+	}
+	defer fp.Close()
+	unknown := make(UnknownChroms)
+	defer unknown.Report(clinvarFile)
+	reader := bufio.NewReader(fp)
+	for {
+		var line []byte
+		line, err = reader.ReadBytes('\n')
+		if err != nil && err != io.EOF {
+			return
+		}
+		eof := err == io.EOF
+		err = nil
+		line = bytes.TrimSpace(line)
+		if len(line) > 0 && line[0] != '#' {
+			var record VcfRecord
+			if record, err = NewVcfRecord(string(line)); err != nil {
+				return
+			}
+			if !IsKnownChrom(record.Chrom) {
+				unknown[record.Chrom]++
+			} else if err = db.addRecord(record, reference); err != nil {
+				return
+			}
+		}
+		if eof {
+			break
+		}
+	}
+	for _, positions := range db.Pathogenics {
+		sort.Ints(positions)
+	}
+	return
+}
+
+// GetClinvar 获取与标准化变异一致的ClinVar记录
+func (db ClinvarDB) GetClinvar(variant Variant) (Clinvar, bool) {
+	clinvar, ok := db.Variants[variant.GetSn()]
+	return clinvar, ok
+}
+
+// CountPathogenic 统计区间[start, end]内的致病变异数
+func (db ClinvarDB) CountPathogenic(chrom string, start int, end int) int {
+	positions := db.Pathogenics[chrom]
+	left := sort.SearchInts(positions, start)
+	right := sort.SearchInts(positions, end+1)
+	return right - left
+}
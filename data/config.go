@@ -1,57 +1,106 @@
 package data
 
 import (
+	"fmt"
 	"io/ioutil"
+	"sort"
+	"strings"
 
 	"gopkg.in/yaml.v2"
 )
 
-// ChromMaxLen 最大染色体长度
+// ChromMaxLen 染色体位置编码的倍数：大于所有染色体的长度，使不同染色体的编码位置互不重叠
 var ChromMaxLen int = 1000
 
-// Config 配置
-var Config struct {
-	DBFile struct {
-		Reference string `yaml:"reference"`
-		NcbiGene  string `yaml:"ncbi_gene"`
-		Refgene   string `yaml:"refgene"`
-		EnsMt     string `yaml:"ens_mt"`
-		Cds       string `yaml:"cds"`
-		Exon      string `yaml:"exon"`
-		Mrna      string `yaml:"mrna"`
-		Refidx    string `yaml:"refidx"`
-	} `yaml:"db_file"`
-	Param struct {
-		UpDownStream int `yaml:"up_down_stream"`
-		RefidxStep   int `yaml:"refidx_step"`
-		SplicingLen  int `yaml:"splicing_len"`
+// DBFileConfig 数据库文件配置
+type DBFileConfig struct {
+	Reference  string `yaml:"reference"`
+	NcbiGene   string `yaml:"ncbi_gene"`
+	Refgene    string `yaml:"refgene"`
+	EnsMt      string `yaml:"ens_mt"`
+	Cds        string `yaml:"cds"`
+	Exon       string `yaml:"exon"`
+	Mrna       string `yaml:"mrna"`
+	Population string `yaml:"population"`
+	Clinvar    string `yaml:"clinvar"`
+	Dbsnp      string `yaml:"dbsnp"`
+	AnnoDB     string `yaml:"anno_db"`
+	ChromAlias string `yaml:"chrom_alias"`
+}
+
+// ChromConfig 染色体配置，Aliases为染色体的其他名称
+type ChromConfig struct {
+	Name    string   `yaml:"name"`
+	Length  int      `yaml:"length"`
+	Aliases []string `yaml:"aliases"`
+}
+
+// ParConfig 拟常染色体区(PAR)配置，坐标从1开始且包含两端
+type ParConfig struct {
+	Chrom string `yaml:"chrom"`
+	Start int    `yaml:"start"`
+	End   int    `yaml:"end"`
+}
+
+// BuildProfile 基因组版本配置：数据库文件、染色体列表及拟常染色体区
+type BuildProfile struct {
+	DBFile DBFileConfig  `yaml:"db_file"`
+	Chrom  []ChromConfig `yaml:"chrom"`
+	Par    []ParConfig   `yaml:"par"`
+}
+
+// Configuration 配置
+type Configuration struct {
+	Build  string                  `yaml:"build"`
+	Builds map[string]BuildProfile `yaml:"builds"`
+	DBFile DBFileConfig            `yaml:"db_file"`
+	Param  struct {
+		UpDownStream int      `yaml:"up_down_stream"`
+		SplicingLen  int      `yaml:"splicing_len"`
+		Populations  []string `yaml:"populations"`
 	} `yaml:"param"`
-	Chrom []struct {
-		Name   string `yaml:"name"`
-		Length int    `yaml:"length"`
-	} `yaml:"chrom"`
+	Chrom []ChromConfig `yaml:"chrom"`
+	Par   []ParConfig   `yaml:"par"`
 }
 
-// ReadConfigYAML 读取YAML配置文件
-func ReadConfigYAML(yamlFile string) error {
+// Config 当前配置：染色体列表、别名及PAR为进程内全局状态，每次读取配置文件时重置
+var Config Configuration
+
+// ReadConfigYAML 读取YAML配置文件，build不为空时使用该基因组版本的配置，否则使用配置文件中build指定的版本
+func ReadConfigYAML(yamlFile string, build string) error {
 	buffer, err := ioutil.ReadFile(yamlFile)
 	if err != nil {
 		return err
 	}
-	err = yaml.Unmarshal(buffer, &Config)
-	if err != nil {
+	var config Configuration
+	if err = yaml.Unmarshal(buffer, &config); err != nil {
 		return err
 	}
+	Config = config
+	if build != "" {
+		Config.Build = build
+	}
+	if len(Config.Builds) > 0 {
+		profile, ok := Config.Builds[Config.Build]
+		if !ok {
+			var builds []string
+			for name := range Config.Builds {
+				builds = append(builds, name)
+			}
+			sort.Strings(builds)
+			return fmt.Errorf("unknown build %q, available builds: %s", Config.Build, strings.Join(builds, ","))
+		}
+		Config.DBFile, Config.Chrom, Config.Par = profile.DBFile, profile.Chrom, profile.Par
+	}
+	InitChromAliases()
+	ChromMaxLen = 1000
 	maxLen := 0
 	for _, chrom := range Config.Chrom {
 		if maxLen < chrom.Length {
 			maxLen = chrom.Length
 		}
 	}
-	for {
-		if ChromMaxLen/maxLen < 1 {
-			break
-		}
+	for ChromMaxLen <= maxLen {
 		ChromMaxLen *= 1000
 	}
 	return nil
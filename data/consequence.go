@@ -0,0 +1,106 @@
+package data
+
+import (
+	"sort"
+	"strings"
+)
+
+// 变异影响程度，与Ensembl VEP的IMPACT一致
+const (
+	ImpactHigh     = "HIGH"
+	ImpactModerate = "MODERATE"
+	ImpactLow      = "LOW"
+	ImpactModifier = "MODIFIER"
+)
+
+// consequenceTerm Sequence Ontology consequence term及其影响程度
+type consequenceTerm struct {
+	Term   string
+	Impact string
+}
+
+// consequenceTerms 按严重程度从高到低排列的Sequence Ontology consequence terms
+var consequenceTerms = []consequenceTerm{
+	{"transcript_ablation", ImpactHigh},
+	{"exon_loss_variant", ImpactHigh},
+	{"splice_acceptor_variant", ImpactHigh},
+	{"splice_donor_variant", ImpactHigh},
+	{"stop_gained", ImpactHigh},
+	{"frameshift_variant", ImpactHigh},
+	{"stop_lost", ImpactHigh},
+	{"start_lost", ImpactHigh},
+	{"transcript_amplification", ImpactHigh},
+	{"inframe_insertion", ImpactModerate},
+	{"inframe_deletion", ImpactModerate},
+	{"missense_variant", ImpactModerate},
+	{"protein_altering_variant", ImpactModerate},
+	{"splice_region_variant", ImpactLow},
+	{"stop_retained_variant", ImpactLow},
+	{"synonymous_variant", ImpactLow},
+	{"coding_sequence_variant", ImpactModifier},
+	{"5_prime_UTR_variant", ImpactModifier},
+	{"3_prime_UTR_variant", ImpactModifier},
+	{"non_coding_transcript_exon_variant", ImpactModifier},
+	{"intron_variant", ImpactModifier},
+	{"non_coding_transcript_variant", ImpactModifier},
+	{"upstream_gene_variant", ImpactModifier},
+	{"downstream_gene_variant", ImpactModifier},
+	{"feature_elongation", ImpactModifier},
+	{"feature_truncation", ImpactModifier},
+	{"intergenic_variant", ImpactModifier},
+}
+
+// consequenceRanks consequence term到严重程度序号(越小越严重)的映射
+var consequenceRanks = make(map[string]int)
+
+func init() {
+	for rank, term := range consequenceTerms {
+		consequenceRanks[term.Term] = rank
+	}
+}
+
+// getConsequenceRank 获取consequence term的严重程度序号，未知term排在最后
+func getConsequenceRank(term string) int {
+	if rank, ok := consequenceRanks[term]; ok {
+		return rank
+	}
+	return len(consequenceTerms)
+}
+
+// GetConsequenceImpact 获取consequence term的影响程度，未知term为MODIFIER
+func GetConsequenceImpact(term string) string {
+	if rank, ok := consequenceRanks[term]; ok {
+		return consequenceTerms[rank].Impact
+	}
+	return ImpactModifier
+}
+
+// SortConsequences 去除重复的consequence term并按严重程度从高到低排序
+func SortConsequences(terms []string) []string {
+	visited := make(map[string]bool)
+	var sorted []string
+	for _, term := range terms {
+		if term != "" && !visited[term] {
+			visited[term] = true
+			sorted = append(sorted, term)
+		}
+	}
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return getConsequenceRank(sorted[i]) < getConsequenceRank(sorted[j])
+	})
+	return sorted
+}
+
+// GetMostSevereConsequence 获取最严重的consequence term及其影响程度，terms为空时返回空字符串
+func GetMostSevereConsequence(terms []string) (string, string) {
+	sorted := SortConsequences(terms)
+	if len(sorted) == 0 {
+		return "", ""
+	}
+	return sorted[0], GetConsequenceImpact(sorted[0])
+}
+
+// JoinConsequences 以&连接consequence terms(与VEP的Consequence字段一致)
+func JoinConsequences(terms []string) string {
+	return strings.Join(terms, "&")
+}
@@ -0,0 +1,27 @@
+package data
+
+import (
+	"strings"
+)
+
+// GetRsids 获取匹配记录的dbSNP rsID，ID列为空时使用INFO中的RS字段
+func GetRsids(matches []VcfMatch) []string {
+	var rsids []string
+	exists := make(map[string]bool)
+	for _, match := range matches {
+		ids := strings.Split(match.Record.ID, ";")
+		if match.Record.ID == "." || match.Record.ID == "" {
+			ids = nil
+			if rs, ok := match.Record.GetInfo("RS"); ok {
+				ids = append(ids, "rs"+rs)
+			}
+		}
+		for _, id := range ids {
+			if id != "" && id != "." && !exists[id] {
+				exists[id] = true
+				rsids = append(rsids, id)
+			}
+		}
+	}
+	return rsids
+}
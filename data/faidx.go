@@ -0,0 +1,197 @@
+package data
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+)
+
+// faidxEntry Fasta索引(.fai)中的一条序列
+type faidxEntry struct {
+	Name      string
+	Length    int
+	Offset    int64
+	LineBases int
+	LineWidth int
+}
+
+// Faidx 带.fai索引的Fasta文件，按区域随机读取序列
+type Faidx struct {
+	File    string
+	Names   []string
+	fp      *os.File
+	entries map[string]faidxEntry
+}
+
+// BuildFaidx 为Fasta文件创建.fai索引文件
+func BuildFaidx(fastaFile string) error {
+	log.Printf("start build %s.fai\n", fastaFile)
+	if isCompressedFasta(fastaFile) {
+		return errors.New("faidx does not support compressed fasta, please decompress it: " + fastaFile)
+	}
+	fp, err := os.Open(fastaFile)
+	if err != nil {
+		return err
+	}
+	defer fp.Close()
+	var entries []faidxEntry
+	var entry *faidxEntry
+	var offset int64
+	lastLine := false
+	reader := bufio.NewReader(fp)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err != nil && err != io.EOF {
+			return err
+		}
+		if len(line) > 0 {
+			content := bytes.TrimRight(line, "\r\n")
+			if len(content) > 0 && content[0] == '>' {
+				entries = append(entries, faidxEntry{
+					Name:   strings.Fields(string(content[1:]) + " ")[0],
+					Offset: offset + int64(len(line)),
+				})
+				entry = &entries[len(entries)-1]
+				lastLine = false
+			} else if entry != nil && len(content) > 0 {
+				if lastLine {
+					return fmt.Errorf("different line length in sequence %s", entry.Name)
+				}
+				if entry.LineBases == 0 {
+					entry.LineBases, entry.LineWidth = len(content), len(line)
+				} else if len(content) != entry.LineBases {
+					lastLine = true
+				}
+				if len(content) > entry.LineBases {
+					return fmt.Errorf("different line length in sequence %s", entry.Name)
+				}
+				entry.Length += len(content)
+			}
+			offset += int64(len(line))
+		}
+		if err == io.EOF {
+			break
+		}
+	}
+	fo, err := os.Create(fastaFile + ".fai")
+	if err != nil {
+		return err
+	}
+	defer fo.Close()
+	for _, entry := range entries {
+		if _, err := fo.WriteString(fmt.Sprintf("%s\t%d\t%d\t%d\t%d\n", entry.Name, entry.Length, entry.Offset, entry.LineBases, entry.LineWidth)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// isCompressedFasta Fasta文件是否为压缩文件
+func isCompressedFasta(fastaFile string) bool {
+	return strings.HasSuffix(strings.ToLower(fastaFile), ".gz")
+}
+
+// PrepareFaidx 为Fasta文件创建.fai索引文件，索引已存在时跳过
+func PrepareFaidx(fastaFile string) error {
+	if _, err := os.Stat(fastaFile + ".fai"); !os.IsNotExist(err) {
+		return err
+	}
+	return BuildFaidx(fastaFile)
+}
+
+// OpenFaidx 打开Fasta文件及其.fai索引，索引由pre生成，不存在时报错
+func OpenFaidx(fastaFile string) (*Faidx, error) {
+	if isCompressedFasta(fastaFile) {
+		return nil, errors.New("faidx does not support compressed fasta, please decompress it: " + fastaFile)
+	}
+	if _, err := os.Stat(fastaFile + ".fai"); os.IsNotExist(err) {
+		return nil, fmt.Errorf("%s.fai is missing, please run pre", fastaFile)
+	}
+	lines, err := ReadFile(fastaFile + ".fai")
+	if err != nil {
+		return nil, err
+	}
+	faidx := &Faidx{File: fastaFile, entries: make(map[string]faidxEntry)}
+	for _, line := range lines {
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 {
+			continue
+		}
+		field := strings.Split(string(line), "\t")
+		if len(field) < 5 {
+			return nil, errors.New("invalid fai line: " + string(line))
+		}
+		ints, err := Strs2Ints([]string{field[1], field[2], field[3], field[4]})
+		if err != nil {
+			return nil, err
+		}
+		faidx.entries[field[0]] = faidxEntry{
+			Name:      field[0],
+			Length:    ints[0],
+			Offset:    int64(ints[1]),
+			LineBases: ints[2],
+			LineWidth: ints[3],
+		}
+		faidx.Names = append(faidx.Names, field[0])
+	}
+	if faidx.fp, err = os.Open(fastaFile); err != nil {
+		return nil, err
+	}
+	return faidx, nil
+}
+
+// Close 关闭Fasta文件
+func (faidx *Faidx) Close() error {
+	return faidx.fp.Close()
+}
+
+// getEntry 获取序列索引(兼容染色体别名)
+func (faidx *Faidx) getEntry(chrom string) (faidxEntry, bool) {
+	if entry, ok := faidx.entries[chrom]; ok {
+		return entry, true
+	}
+	for _, candidate := range GetChromAliases(chrom) {
+		if entry, ok := faidx.entries[candidate]; ok {
+			return entry, true
+		}
+	}
+	return faidxEntry{}, false
+}
+
+// GetLength 获取序列长度
+func (faidx *Faidx) GetLength(chrom string) (int, bool) {
+	entry, ok := faidx.getEntry(chrom)
+	return entry.Length, ok
+}
+
+// GetSeq 获取区间[start, end](1-based)的序列，超出序列范围的部分截断
+func (faidx *Faidx) GetSeq(chrom string, start int, end int) (Sequence, error) {
+	entry, ok := faidx.getEntry(chrom)
+	if !ok {
+		return "", errors.New("Not Found: " + chrom)
+	}
+	if start < 1 {
+		start = 1
+	}
+	if end > entry.Length {
+		end = entry.Length
+	}
+	if start > end {
+		return "", nil
+	}
+	offsetOf := func(pos int) int64 {
+		return entry.Offset + int64(pos/entry.LineBases*entry.LineWidth+pos%entry.LineBases)
+	}
+	begin, stop := offsetOf(start-1), offsetOf(end-1)+1
+	buffer := make([]byte, stop-begin)
+	if _, err := faidx.fp.ReadAt(buffer, begin); err != nil && err != io.EOF {
+		return "", err
+	}
+	seq := bytes.ToUpper(bytes.Replace(bytes.Replace(buffer, []byte{'\n'}, nil, -1), []byte{'\r'}, nil, -1))
+	return Sequence(seq), nil
+}
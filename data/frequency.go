@@ -0,0 +1,57 @@
+package data
+
+import (
+	"strconv"
+)
+
+// Frequency 人群等位基因频率
+type Frequency struct {
+	AF float64 `json:"af"`
+	AC int     `json:"ac"`
+	AN int     `json:"an"`
+}
+
+// GetFrequency 从VCF记录中获取指定人群的频率，population为空时获取总体频率(AF/AC/AN)
+func (match VcfMatch) GetFrequency(population string) (frequency Frequency, ok bool) {
+	suffix := ""
+	if population != "" {
+		suffix = "_" + population
+	}
+	frequency = Frequency{AF: -1, AC: -1, AN: -1}
+	if value, has := match.Record.GetInfoOfAlt("AF"+suffix, match.AltIndex); has {
+		if af, err := strconv.ParseFloat(value, 64); err == nil {
+			frequency.AF, ok = af, true
+		}
+	}
+	if value, has := match.Record.GetInfoOfAlt("AC"+suffix, match.AltIndex); has {
+		if ac, err := strconv.Atoi(value); err == nil {
+			frequency.AC, ok = ac, true
+		}
+	}
+	if value, has := match.Record.GetInfo("AN" + suffix); has {
+		if an, err := strconv.Atoi(value); err == nil {
+			frequency.AN, ok = an, true
+		}
+	}
+	if frequency.AF < 0 && frequency.AC >= 0 && frequency.AN > 0 {
+		frequency.AF = float64(frequency.AC) / float64(frequency.AN)
+	}
+	return
+}
+
+// GetFrequencies 获取总体("all")及各人群的频率
+func GetFrequencies(matches []VcfMatch, populations []string) map[string]Frequency {
+	frequencies := make(map[string]Frequency)
+	if len(matches) == 0 {
+		return frequencies
+	}
+	if frequency, ok := matches[0].GetFrequency(""); ok {
+		frequencies["all"] = frequency
+	}
+	for _, population := range populations {
+		if frequency, ok := matches[0].GetFrequency(population); ok {
+			frequencies[population] = frequency
+		}
+	}
+	return frequencies
+}
@@ -0,0 +1,307 @@
+package data
+
+import (
+	"bytes"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// gffTranscript GTF/GFF3文件中的转录本
+type gffTranscript struct {
+	Chrom      string
+	Strand     byte
+	Gene       string
+	Transcript string
+	ExonStarts []int
+	ExonEnds   []int
+	CdsStart   int
+	CdsEnd     int
+	Incmpl     bool
+}
+
+// addCds 添加CDS区域(包括起始和终止密码子)
+func (transcript *gffTranscript) addCds(start int, end int) {
+	if transcript.CdsStart == 0 || start < transcript.CdsStart {
+		transcript.CdsStart = start
+	}
+	if end > transcript.CdsEnd {
+		transcript.CdsEnd = end
+	}
+}
+
+// toRefgene 转为Refgene
+func (transcript *gffTranscript) toRefgene() (refgene Refgene, err error) {
+	if len(transcript.ExonStarts) == 0 {
+		err = fmt.Errorf("transcript %s has no exon", transcript.Transcript)
+		return
+	}
+	exons := make(Regions, len(transcript.ExonStarts))
+	for i := range transcript.ExonStarts {
+		exons[i] = Region{Start: transcript.ExonStarts[i], End: transcript.ExonEnds[i]}
+	}
+	sort.Sort(exons)
+	refgene = Refgene{
+		Chrom:      ConvertChrom(transcript.Chrom),
+		Strand:     transcript.Strand,
+		Gene:       transcript.Gene,
+		Transcript: transcript.Transcript,
+		ExonStart:  exons[0].Start,
+		ExonEnd:    exons[len(exons)-1].End,
+	}
+	for _, exon := range exons {
+		refgene.ExonStarts = append(refgene.ExonStarts, exon.Start)
+		refgene.ExonEnds = append(refgene.ExonEnds, exon.End)
+	}
+	switch {
+	case transcript.CdsStart == 0:
+		refgene.CdsStart, refgene.CdsEnd = refgene.ExonEnd+1, refgene.ExonEnd
+		refgene.Tag = "unk"
+	case transcript.Incmpl:
+		refgene.CdsStart, refgene.CdsEnd = transcript.CdsStart, transcript.CdsEnd
+		refgene.Tag = "incmpl"
+	default:
+		refgene.CdsStart, refgene.CdsEnd = transcript.CdsStart, transcript.CdsEnd
+		refgene.Tag = "cmpl"
+	}
+	refgene.SetRegions()
+	refgene.SetUpDownStream(Config.Param.UpDownStream)
+	return
+}
+
+// parseGtfAttributes 解析GTF第9列属性：key "value"; key "value";
+func parseGtfAttributes(field string) map[string][]string {
+	attributes := make(map[string][]string)
+	for _, item := range strings.Split(field, ";") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		index := strings.IndexAny(item, " \t")
+		if index < 0 {
+			continue
+		}
+		key, value := item[:index], strings.Trim(strings.TrimSpace(item[index+1:]), `"`)
+		attributes[key] = append(attributes[key], value)
+	}
+	return attributes
+}
+
+// parseGff3Attributes 解析GFF3第9列属性：key=value1,value2;key=value
+func parseGff3Attributes(field string) map[string][]string {
+	attributes := make(map[string][]string)
+	for _, item := range strings.Split(field, ";") {
+		item = strings.TrimSpace(item)
+		index := strings.IndexByte(item, '=')
+		if index < 0 {
+			continue
+		}
+		for _, value := range strings.Split(item[index+1:], ",") {
+			if unescaped, err := url.PathUnescape(value); err == nil {
+				value = unescaped
+			}
+			attributes[item[:index]] = append(attributes[item[:index]], value)
+		}
+	}
+	return attributes
+}
+
+// getAttribute 获取第一个非空属性值
+func getAttribute(attributes map[string][]string, keys ...string) string {
+	for _, key := range keys {
+		if values, ok := attributes[key]; ok && len(values) > 0 && values[0] != "" {
+			return values[0]
+		}
+	}
+	return ""
+}
+
+// hasIncmplTag 是否含有CDS不完整的标签
+func hasIncmplTag(attributes map[string][]string) bool {
+	for _, tag := range attributes["tag"] {
+		if tag == "cds_start_NF" || tag == "cds_end_NF" || tag == "mRNA_start_NF" || tag == "mRNA_end_NF" {
+			return true
+		}
+	}
+	return false
+}
+
+// gffLine GTF/GFF3中的一行
+type gffLine struct {
+	Chrom   string
+	Feature string
+	Start   int
+	End     int
+	Strand  byte
+	Field   string
+}
+
+// readGffLines 读取GTF/GFF3文件中的特征行
+func readGffLines(gffFile string) (gffLines []gffLine, err error) {
+	lines, err := ReadFile(gffFile)
+	if err != nil {
+		return
+	}
+	for _, line := range lines {
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 || line[0] == '#' {
+			continue
+		}
+		field := strings.Split(string(line), "\t")
+		if len(field) < 9 {
+			err = fmt.Errorf("invalid gtf/gff3 line: %s", line)
+			return
+		}
+		gff := gffLine{Chrom: field[0], Feature: field[2], Strand: field[6][0], Field: field[8]}
+		if gff.Start, err = strconv.Atoi(field[3]); err != nil {
+			return
+		}
+		if gff.End, err = strconv.Atoi(field[4]); err != nil {
+			return
+		}
+		gffLines = append(gffLines, gff)
+	}
+	return
+}
+
+// toRefgenes 将转录本转为Refgenes
+func toRefgenes(transcriptIDs []string, transcriptMap map[string]*gffTranscript) (refgenes Refgenes, err error) {
+	for _, transcriptID := range transcriptIDs {
+		var refgene Refgene
+		if refgene, err = transcriptMap[transcriptID].toRefgene(); err != nil {
+			return
+		}
+		refgenes = append(refgenes, refgene)
+	}
+	return
+}
+
+// ReadGtfFile 读取GTF文件(GENCODE/Ensembl)，按transcript_id组装Refgene
+func ReadGtfFile(gtfFile string) (refgenes Refgenes, err error) {
+	gffLines, err := readGffLines(gtfFile)
+	if err != nil {
+		return
+	}
+	var transcriptIDs []string
+	transcriptMap := make(map[string]*gffTranscript)
+	for _, gff := range gffLines {
+		if gff.Feature != "exon" && gff.Feature != "CDS" && gff.Feature != "start_codon" && gff.Feature != "stop_codon" {
+			continue
+		}
+		attributes := parseGtfAttributes(gff.Field)
+		transcriptID := getAttribute(attributes, "transcript_id")
+		if transcriptID == "" {
+			continue
+		}
+		transcript, ok := transcriptMap[transcriptID]
+		if !ok {
+			transcript = &gffTranscript{
+				Chrom:      gff.Chrom,
+				Strand:     gff.Strand,
+				Gene:       getAttribute(attributes, "gene_name", "gene_id"),
+				Transcript: transcriptID,
+			}
+			transcriptMap[transcriptID] = transcript
+			transcriptIDs = append(transcriptIDs, transcriptID)
+		}
+		if hasIncmplTag(attributes) {
+			transcript.Incmpl = true
+		}
+		if gff.Feature == "exon" {
+			transcript.ExonStarts = append(transcript.ExonStarts, gff.Start)
+			transcript.ExonEnds = append(transcript.ExonEnds, gff.End)
+		} else {
+			transcript.addCds(gff.Start, gff.End)
+		}
+	}
+	return toRefgenes(transcriptIDs, transcriptMap)
+}
+
+// ReadGff3File 读取GFF3文件(GENCODE/Ensembl/RefSeq)，按exon/CDS的Parent组装Refgene
+func ReadGff3File(gff3File string) (refgenes Refgenes, err error) {
+	gffLines, err := readGffLines(gff3File)
+	if err != nil {
+		return
+	}
+	// 第一遍读取基因
+	geneNames := make(map[string]string)
+	var transcriptIDs []string
+	transcriptMap := make(map[string]*gffTranscript)
+	for _, gff := range gffLines {
+		attributes := parseGff3Attributes(gff.Field)
+		id := getAttribute(attributes, "ID")
+		if id == "" {
+			continue
+		}
+		if gff.Feature == "gene" || gff.Feature == "ncRNA_gene" || gff.Feature == "pseudogene" {
+			geneNames[id] = getAttribute(attributes, "gene_name", "Name", "gene", "gene_id")
+		}
+	}
+	// 第二遍读取转录本
+	for _, gff := range gffLines {
+		if gff.Feature == "exon" || gff.Feature == "CDS" || gff.Feature == "start_codon" || gff.Feature == "stop_codon" {
+			continue
+		}
+		attributes := parseGff3Attributes(gff.Field)
+		id, parent := getAttribute(attributes, "ID"), getAttribute(attributes, "Parent")
+		if id == "" || parent == "" {
+			continue
+		}
+		if _, ok := geneNames[parent]; !ok {
+			continue
+		}
+		gene := getAttribute(attributes, "gene_name", "gene")
+		if gene == "" {
+			gene = geneNames[parent]
+		}
+		transcriptMap[id] = &gffTranscript{
+			Chrom:      gff.Chrom,
+			Strand:     gff.Strand,
+			Gene:       gene,
+			Transcript: getAttribute(attributes, "transcript_id", "Name", "ID"),
+			Incmpl:     hasIncmplTag(attributes),
+		}
+		transcriptIDs = append(transcriptIDs, id)
+	}
+	// 第三遍读取外显子和CDS
+	for _, gff := range gffLines {
+		if gff.Feature != "exon" && gff.Feature != "CDS" && gff.Feature != "start_codon" && gff.Feature != "stop_codon" {
+			continue
+		}
+		attributes := parseGff3Attributes(gff.Field)
+		for _, parent := range attributes["Parent"] {
+			transcript, ok := transcriptMap[parent]
+			if !ok {
+				continue
+			}
+			if gff.Feature == "exon" {
+				transcript.ExonStarts = append(transcript.ExonStarts, gff.Start)
+				transcript.ExonEnds = append(transcript.ExonEnds, gff.End)
+			} else {
+				transcript.addCds(gff.Start, gff.End)
+			}
+		}
+	}
+	var ids []string
+	for _, id := range transcriptIDs {
+		if len(transcriptMap[id].ExonStarts) > 0 {
+			ids = append(ids, id)
+		}
+	}
+	return toRefgenes(ids, transcriptMap)
+}
+
+// GetRefgeneFormat 根据文件后缀获取转录本文件格式：gtf、gff3或refgene
+func GetRefgeneFormat(refgeneFile string) string {
+	name := strings.TrimSuffix(strings.ToLower(refgeneFile), ".gz")
+	switch {
+	case strings.HasSuffix(name, ".gtf"):
+		return "gtf"
+	case strings.HasSuffix(name, ".gff3") || strings.HasSuffix(name, ".gff"):
+		return "gff3"
+	default:
+		return "refgene"
+	}
+}
@@ -0,0 +1,154 @@
+package data
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"path"
+	"strings"
+)
+
+// ManifestFile 数据库清单文件名，位于数据库目录中
+const ManifestFile = "manifest.json"
+
+// Manifest 数据库清单：记录pre生成数据库时的文件信息及配置参数
+type Manifest struct {
+	Build string `json:"build"`
+	Param struct {
+		UpDownStream int `json:"up_down_stream"`
+	} `json:"param"`
+	Files []AnnoDBSource `json:"files"`
+}
+
+// GetDBFileNames 获取配置文件中的所有数据库文件名(包括参考基因组的.fai索引)，未配置的文件跳过
+func GetDBFileNames() []string {
+	dbFile := Config.DBFile
+	var names []string
+	referenceIndex := ""
+	if dbFile.Reference != "" {
+		referenceIndex = dbFile.Reference + ".fai"
+	}
+	for _, name := range []string{
+		dbFile.Reference, referenceIndex, dbFile.NcbiGene, dbFile.Refgene, dbFile.EnsMt, dbFile.Cds, dbFile.Exon,
+		dbFile.Mrna, dbFile.Population, dbFile.Clinvar, dbFile.Dbsnp, dbFile.AnnoDB, dbFile.ChromAlias,
+	} {
+		if name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// NewManifest 根据当前配置创建数据库清单，不存在的数据库文件跳过
+func NewManifest(dbPath string) (manifest Manifest, err error) {
+	manifest.Build = Config.Build
+	manifest.Param.UpDownStream = Config.Param.UpDownStream
+	for _, name := range GetDBFileNames() {
+		file := path.Join(dbPath, name)
+		if _, e := os.Stat(file); os.IsNotExist(e) {
+			log.Printf("skip missing file %s\n", file)
+			continue
+		}
+		var source AnnoDBSource
+		if source, err = NewAnnoDBSource(file); err != nil {
+			return
+		}
+		manifest.Files = append(manifest.Files, source)
+	}
+	return
+}
+
+// WriteManifestFile 输出数据库清单文件
+func WriteManifestFile(manifestFile string, manifest Manifest) error {
+	log.Printf("start write %s\n", manifestFile)
+	content, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(manifestFile, append(content, '\n'), 0644)
+}
+
+// ReadManifestFile 读取数据库清单文件
+func ReadManifestFile(manifestFile string) (manifest Manifest, err error) {
+	content, err := ioutil.ReadFile(manifestFile)
+	if err != nil {
+		return
+	}
+	err = json.Unmarshal(content, &manifest)
+	return
+}
+
+// Check 检查数据库目录中的文件及当前配置是否与清单一致，返回不一致的描述
+func (manifest Manifest) Check(dbPath string) (problems []string, err error) {
+	if manifest.Build != Config.Build {
+		problems = append(problems, fmt.Sprintf("build: manifest %q, config %q", manifest.Build, Config.Build))
+	}
+	if manifest.Param.UpDownStream != Config.Param.UpDownStream {
+		problems = append(problems, fmt.Sprintf("up_down_stream: manifest %d, config %d", manifest.Param.UpDownStream, Config.Param.UpDownStream))
+	}
+	files := make(map[string]AnnoDBSource, len(manifest.Files))
+	for _, file := range manifest.Files {
+		files[file.Name] = file
+	}
+	for _, name := range GetDBFileNames() {
+		file := path.Join(dbPath, name)
+		expected, ok := files[path.Base(name)]
+		if _, e := os.Stat(file); os.IsNotExist(e) {
+			if ok {
+				problems = append(problems, fmt.Sprintf("%s: file is missing", name))
+			}
+			continue
+		}
+		if !ok {
+			problems = append(problems, fmt.Sprintf("%s: not in manifest", name))
+			continue
+		}
+		var actual AnnoDBSource
+		if actual, err = NewAnnoDBSource(file); err != nil {
+			return
+		}
+		if actual.Size != expected.Size {
+			problems = append(problems, fmt.Sprintf("%s: size %d, manifest %d", name, actual.Size, expected.Size))
+		} else if actual.SHA256 != expected.SHA256 {
+			problems = append(problems, fmt.Sprintf("%s: sha256 %s, manifest %s", name, actual.SHA256, expected.SHA256))
+		}
+	}
+	return
+}
+
+// ReadFastaLengths 读取Fasta文件中每条序列的长度
+func ReadFastaLengths(fastaFile string) (lengths map[string]int, err error) {
+	lengths = make(map[string]int)
+	fp, err := OpenFile(fastaFile)
+	if err != nil {
+		return
+	}
+	defer fp.Close()
+	reader := bufio.NewReader(fp)
+	var name string
+	for {
+		var line []byte
+		line, err = reader.ReadBytes('\n')
+		if err != nil && err != io.EOF {
+			return
+		}
+		eof := err == io.EOF
+		err = nil
+		line = bytes.TrimSpace(line)
+		if len(line) > 0 && line[0] == '>' {
+			name = strings.Split(string(line[1:]), " ")[0]
+			lengths[name] = 0
+		} else if name != "" {
+			lengths[name] += len(line)
+		}
+		if eof {
+			break
+		}
+	}
+	return
+}
@@ -29,13 +29,16 @@ func (ng NcbiGene) GetEntrezID(symbol string) int {
 }
 
 // ReadNCBIGeneInfo 读取NCBI GENE INFO文件
-func ReadNCBIGeneInfo(ncbiGeneInfoFile string, ncbiGeneChan chan NcbiGene) {
+func ReadNCBIGeneInfo(ncbiGeneInfoFile string) (ncbiGene NcbiGene, err error) {
 	log.Printf("start read %s\n", ncbiGeneInfoFile)
-	var ncbiGene NcbiGene
-	var lines [][]byte
+	ncbiGene = NcbiGene{
+		Symbol:    make(map[string]int),
+		Synonyms:  make(map[string]int),
+		SymbolFna: make(map[string]int),
+	}
 	lines, err := ReadFile(ncbiGeneInfoFile)
 	if err != nil {
-		log.Fatal(err)
+		return
 	}
 	for _, line := range lines {
 		line = bytes.TrimSpace(line)
@@ -44,9 +47,8 @@ func ReadNCBIGeneInfo(ncbiGeneInfoFile string, ncbiGeneChan chan NcbiGene) {
 		}
 		field := strings.Split(string(line), "\t")
 		var entrezID int
-		entrezID, err = strconv.Atoi(field[1])
-		if err != nil {
-			log.Fatal(err)
+		if entrezID, err = strconv.Atoi(field[1]); err != nil {
+			return
 		}
 		symbol, synonyms, symbolFna := field[2], strings.Split(field[4], "|"), field[10]
 		if symbol != "-" && symbol != "." {
@@ -61,5 +63,5 @@ func ReadNCBIGeneInfo(ncbiGeneInfoFile string, ncbiGeneChan chan NcbiGene) {
 			}
 		}
 	}
-	ncbiGeneChan <- ncbiGene
+	return
 }
@@ -0,0 +1,161 @@
+package data
+
+// normalizeWindow 移位时每次读取的参考序列长度
+const normalizeWindow = 64
+
+// referenceCursor 移位时按窗口缓存参考序列
+type referenceCursor struct {
+	reference *Faidx
+	chrom     string
+	length    int
+	start     int
+	seq       Sequence
+}
+
+// getBase 获取位置pos(1-based)的碱基，forward为读取方向
+func (cursor *referenceCursor) getBase(pos int, forward bool) (Base, error) {
+	if pos < cursor.start || pos >= cursor.start+cursor.seq.GetLen() {
+		start, end := pos-normalizeWindow+1, pos
+		if forward {
+			start, end = pos, pos+normalizeWindow-1
+		}
+		seq, err := cursor.reference.GetSeq(cursor.chrom, start, end)
+		if err != nil {
+			return 0, err
+		}
+		if start < 1 {
+			start = 1
+		}
+		cursor.start, cursor.seq = start, seq
+	}
+	return cursor.seq.GetChar(pos - cursor.start), nil
+}
+
+// IsIndel 是否为插入或缺失
+func (variant Variant) IsIndel() bool {
+	return variant.Ref == "-" && variant.Alt != "-" || variant.Alt == "-" && variant.Ref != "-"
+}
+
+// newReferenceCursor 检查变异是否可以在参考基因组上移位：缺失序列需与参考基因组一致
+func (variant Variant) newReferenceCursor(reference *Faidx) (*referenceCursor, bool, error) {
+	if reference == nil || !variant.IsIndel() {
+		return nil, false, nil
+	}
+	length, ok := reference.GetLength(variant.Chrom)
+	if !ok {
+		return nil, false, nil
+	}
+	if variant.Alt == "-" {
+		seq, err := reference.GetSeq(variant.Chrom, variant.Start, variant.End)
+		if err != nil || seq != variant.Ref {
+			return nil, false, err
+		}
+	}
+	return &referenceCursor{reference: reference, chrom: variant.Chrom, length: length}, true, nil
+}
+
+// LeftAlign 在参考基因组上将插入/缺失向左移位到最左侧的等价位置，用于数据库匹配；其他变异保持不变
+func (variant Variant) LeftAlign(reference *Faidx) (Variant, error) {
+	cursor, ok, err := variant.newReferenceCursor(reference)
+	if !ok {
+		return variant, err
+	}
+	if variant.Ref == "-" {
+		alt := []byte(variant.Alt)
+		for variant.Start > 0 {
+			base, err := cursor.getBase(variant.Start, false)
+			if err != nil {
+				return variant, err
+			}
+			if base != alt[len(alt)-1] {
+				break
+			}
+			copy(alt[1:], alt[:len(alt)-1])
+			alt[0] = base
+			variant.Start--
+		}
+		variant.End = variant.Start
+		variant.Alt = Sequence(alt)
+	} else {
+		ref := []byte(variant.Ref)
+		for variant.Start > 1 {
+			base, err := cursor.getBase(variant.Start-1, false)
+			if err != nil {
+				return variant, err
+			}
+			if base != ref[len(ref)-1] {
+				break
+			}
+			copy(ref[1:], ref[:len(ref)-1])
+			ref[0] = base
+			variant.Start--
+			variant.End--
+		}
+		variant.Ref = Sequence(ref)
+	}
+	return variant, nil
+}
+
+// RightAlign 在参考基因组上将插入/缺失向右移位到最右侧的等价位置，用于正链转录本的3'端规则；其他变异保持不变
+func (variant Variant) RightAlign(reference *Faidx) (Variant, error) {
+	cursor, ok, err := variant.newReferenceCursor(reference)
+	if !ok {
+		return variant, err
+	}
+	if variant.Ref == "-" {
+		alt := []byte(variant.Alt)
+		for variant.Start+1 <= cursor.length {
+			base, err := cursor.getBase(variant.Start+1, true)
+			if err != nil {
+				return variant, err
+			}
+			if base != alt[0] {
+				break
+			}
+			copy(alt, alt[1:])
+			alt[len(alt)-1] = base
+			variant.Start++
+		}
+		variant.End = variant.Start
+		variant.Alt = Sequence(alt)
+	} else {
+		ref := []byte(variant.Ref)
+		for variant.End+1 <= cursor.length {
+			base, err := cursor.getBase(variant.End+1, true)
+			if err != nil {
+				return variant, err
+			}
+			if base != ref[0] {
+				break
+			}
+			copy(ref, ref[1:])
+			ref[len(ref)-1] = base
+			variant.Start++
+			variant.End++
+		}
+		variant.Ref = Sequence(ref)
+	}
+	return variant, nil
+}
+
+// IsDuplication 插入序列是否与参考基因组上相邻的序列一致：forward为true时比较插入位置左侧(正链转录本的5'侧)，否则比较右侧
+func (variant Variant) IsDuplication(reference *Faidx, forward bool) (bool, error) {
+	if reference == nil || variant.Ref != "-" || variant.Alt == "-" {
+		return false, nil
+	}
+	if _, ok := reference.GetLength(variant.Chrom); !ok {
+		return false, nil
+	}
+	start, end := variant.Start+1, variant.Start+variant.Alt.GetLen()
+	if forward {
+		start, end = variant.Start-variant.Alt.GetLen()+1, variant.Start
+	}
+	if start < 1 {
+		return false, nil
+	}
+	seq, err := reference.GetSeq(variant.Chrom, start, end)
+	if err != nil {
+		return false, err
+	}
+	return seq == variant.Alt, nil
+}
@@ -0,0 +1,126 @@
+package data
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// BedRegions BED区域，以染色体为Key，坐标转换为从1开始且包含两端，按起始位置排序并合并重叠区域
+type BedRegions map[string][][2]int
+
+// ReadBedFile 读取BED文件，跳过track/browser/#开头的行及不在当前基因组版本中的染色体
+func ReadBedFile(bedFile string) (regions BedRegions, err error) {
+	log.Printf("start read %s\n", bedFile)
+	regions = make(BedRegions)
+	lines, err := ReadFile(bedFile)
+	if err != nil {
+		return
+	}
+	unknown := make(UnknownChroms)
+	defer unknown.Report(bedFile)
+	for _, line := range lines {
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 || line[0] == '#' || bytes.HasPrefix(line, []byte("track")) || bytes.HasPrefix(line, []byte("browser")) {
+			continue
+		}
+		field := strings.Fields(string(line))
+		if len(field) < 3 {
+			return nil, fmt.Errorf("invalid bed line: %s", line)
+		}
+		if !IsKnownChrom(field[0]) {
+			unknown[field[0]]++
+			continue
+		}
+		var pos []int
+		if pos, err = Strs2Ints(field[1:3]); err != nil {
+			return
+		}
+		chrom := ConvertChrom(field[0])
+		regions[chrom] = append(regions[chrom], [2]int{pos[0] + 1, pos[1]})
+	}
+	for chrom, chromRegions := range regions {
+		sort.Slice(chromRegions, func(i, j int) bool {
+			return chromRegions[i][0] < chromRegions[j][0]
+		})
+		merged := chromRegions[:0]
+		for _, region := range chromRegions {
+			if n := len(merged); n > 0 && region[0] <= merged[n-1][1]+1 {
+				if region[1] > merged[n-1][1] {
+					merged[n-1][1] = region[1]
+				}
+			} else {
+				merged = append(merged, region)
+			}
+		}
+		regions[chrom] = merged
+	}
+	return
+}
+
+// Overlaps 区间[start, end]是否与BED区域重叠
+func (regions BedRegions) Overlaps(chrom string, start int, end int) bool {
+	chromRegions := regions[ConvertChrom(chrom)]
+	i := sort.Search(len(chromRegions), func(i int) bool {
+		return chromRegions[i][1] >= start
+	})
+	return i < len(chromRegions) && chromRegions[i][0] <= end
+}
+
+// ReadGeneList 读取基因列表：value为文件时读取文件中以空白或逗号分隔的基因，否则value为以逗号分隔的基因
+func ReadGeneList(value string) ([]string, error) {
+	if info, err := os.Stat(value); err != nil || info.IsDir() {
+		return strings.FieldsFunc(value, func(c rune) bool { return c == ',' }), nil
+	}
+	log.Printf("start read %s\n", value)
+	lines, err := ReadFile(value)
+	if err != nil {
+		return nil, err
+	}
+	var genes []string
+	for _, line := range lines {
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 || line[0] == '#' {
+			continue
+		}
+		genes = append(genes, strings.FieldsFunc(string(line), func(c rune) bool {
+			return c == ',' || c == ' ' || c == '\t'
+		})...)
+	}
+	return genes, nil
+}
+
+// GenePanel 基因panel：以Entrez ID及无法转换为Entrez ID的Gene symbol表示
+type GenePanel struct {
+	EntrezIDs map[int]bool
+	Symbols   map[string]bool
+}
+
+// NewGenePanel 通过NCBI GENE INFO将基因(Gene symbol或Entrez ID)转换为基因panel，无法转换的Gene symbol按名称匹配
+func NewGenePanel(genes []string, ncbiGene NcbiGene) GenePanel {
+	panel := GenePanel{EntrezIDs: make(map[int]bool), Symbols: make(map[string]bool)}
+	var unknown []string
+	for _, gene := range genes {
+		if entrezID, err := strconv.Atoi(gene); err == nil {
+			panel.EntrezIDs[entrezID] = true
+		} else if entrezID := ncbiGene.GetEntrezID(gene); entrezID > 0 {
+			panel.EntrezIDs[entrezID] = true
+		} else {
+			panel.Symbols[gene] = true
+			unknown = append(unknown, gene)
+		}
+	}
+	if len(unknown) > 0 {
+		log.Printf("genes not found in ncbi gene info: %s\n", strings.Join(unknown, ","))
+	}
+	return panel
+}
+
+// Contains 转录本的基因是否在panel中
+func (panel GenePanel) Contains(refgene Refgene) bool {
+	return refgene.EntrezID > 0 && panel.EntrezIDs[refgene.EntrezID] || panel.Symbols[refgene.Gene]
+}
@@ -0,0 +1,72 @@
+package data
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"strings"
+)
+
+// PedSample PED文件中的个体：Father/Mother为父母的个体ID(0或空表示未知)，Affected为是否患病(表型为2)
+type PedSample struct {
+	Family   string `json:"family"`
+	ID       string `json:"id"`
+	Father   string `json:"father"`
+	Mother   string `json:"mother"`
+	Sex      string `json:"sex"`
+	Affected bool   `json:"affected"`
+}
+
+// Pedigree 个体ID到PED个体的映射
+type Pedigree map[string]PedSample
+
+// getPedParent 获取PED中的父母ID，0表示未知
+func getPedParent(id string) string {
+	if id == "0" {
+		return ""
+	}
+	return id
+}
+
+// ReadPedFile 读取PED文件(家系ID、个体ID、父亲ID、母亲ID、性别、表型，以空白分隔)，跳过#开头的行
+func ReadPedFile(pedFile string) (pedigree Pedigree, err error) {
+	log.Printf("start read %s\n", pedFile)
+	pedigree = make(Pedigree)
+	lines, err := ReadFile(pedFile)
+	if err != nil {
+		return
+	}
+	for _, line := range lines {
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 || line[0] == '#' {
+			continue
+		}
+		field := strings.Fields(string(line))
+		if len(field) < 5 {
+			return nil, fmt.Errorf("invalid ped line: %s", line)
+		}
+		sample := PedSample{
+			Family: field[0],
+			ID:     field[1],
+			Father: getPedParent(field[2]),
+			Mother: getPedParent(field[3]),
+			Sex:    ParseSex(field[4]),
+		}
+		if len(field) > 5 {
+			sample.Affected = field[5] == "2"
+		}
+		pedigree[sample.ID] = sample
+	}
+	return
+}
+
+// GetSexes 获取PED中已知性别的个体性别
+func (pedigree Pedigree) GetSexes() map[string]string {
+	sexes := make(map[string]string)
+	for id, sample := range pedigree {
+		if sample.Sex != SexUnknown {
+			sexes[id] = sample.Sex
+		}
+	}
+	return sexes
+}
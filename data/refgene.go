@@ -2,8 +2,8 @@ package data
 
 import (
 	"bufio"
+	"bytes"
 	"fmt"
-	"io"
 	"log"
 	"os"
 	"sort"
@@ -41,6 +41,19 @@ func (refgene Refgene) IsCmpl() bool {
 	return refgene.Tag == "cmpl"
 }
 
+// GetExonRegion 根据外显子编号获取Refgene外显子区间
+func (refgene Refgene) GetExonRegion(exonOrder int) (start int, end int, ok bool) {
+	exonNum := len(refgene.ExonStarts)
+	if exonOrder < 1 || exonOrder > exonNum {
+		return 0, 0, false
+	}
+	index := exonOrder - 1
+	if refgene.Strand == '-' {
+		index = exonNum - exonOrder
+	}
+	return refgene.ExonStarts[index], refgene.ExonEnds[index], true
+}
+
 // GetNumericalPosition 获取数值位置
 func (refgene Refgene) GetNumericalPosition() (int, int) {
 	order, _ := GetChromByName(refgene.Chrom)
@@ -66,7 +79,7 @@ func (refgene *Refgene) SetRegions() {
 		if refgene.Strand == '+' {
 			exonOrder = i + 1
 		} else {
-			exonOrder = exonNum - 1
+			exonOrder = exonNum - i
 		}
 		start, end := refgene.ExonStarts[i], refgene.ExonEnds[i]
 		if refgene.CdsStart > end || refgene.CdsEnd < start ||
@@ -100,7 +113,7 @@ func (refgene *Refgene) SetRegions() {
 				if refgene.Strand == '+' {
 					utrTypo1 = "utr5"
 				} else {
-					utrTypo2 = "utr3"
+					utrTypo1 = "utr3"
 				}
 				cdsStart = refgene.CdsStart
 			}
@@ -165,18 +178,20 @@ func (refgene *Refgene) SetSequence(mrna Sequence) {
 	if !mrna.IsEmpty() {
 		refgene.Mrna = mrna
 		if refgene.Tag != "unk" {
+			var cdsSeqs []Sequence
 			for _, region := range refgene.Regions {
 				if region.Typo == "cds" {
 					seq := refgene.Mrna.GetSeq(region.Start-refgene.ExonStart, region.End-region.Start+1)
-					refgene.Cdna.Join([]Sequence{seq})
+					cdsSeqs = append(cdsSeqs, seq)
 				}
 			}
+			refgene.Cdna.Join(cdsSeqs)
 			if refgene.Strand == '-' {
-				refgene.Cdna.Reverse()
+				refgene.Cdna.ReverseComplement()
 			}
 		}
 		if !refgene.Cdna.IsEmpty() {
-			refgene.Protein = refgene.Cdna.Translate(refgene.Chrom == "MT")
+			refgene.Protein = refgene.Cdna.Translate(IsMitochondrion(refgene.Chrom))
 			if refgene.Protein.IsCmpl() {
 				refgene.Tag = "cmpl"
 			} else {
@@ -201,11 +216,11 @@ func NewRefgene(refgeneLine string) (refgene Refgene, err error) {
 	for i := range startInts {
 		startInts[i]++
 	}
-	if endInts, err = Strs2Ints(strings.Split(strings.Trim(field[9], ","), ",")); err != nil {
+	if endInts, err = Strs2Ints(strings.Split(strings.Trim(field[10], ","), ",")); err != nil {
 		return
 	}
 	refgene = Refgene{
-		Chrom:      strings.Replace(field[2], "chr", "", 1),
+		Chrom:      ConvertChrom(field[2]),
 		Transcript: field[1],
 		Strand:     field[3][0],
 		Gene:       field[12],
@@ -228,6 +243,7 @@ type Refgenes []Refgene
 
 // ToSnMap 转为sn编号为Key的Map集合
 func (refgenes Refgenes) ToSnMap() (refgeneMap map[string]Refgene) {
+	refgeneMap = make(map[string]Refgene, len(refgenes))
 	for _, refgene := range refgenes {
 		refgeneMap[refgene.GetSn()] = refgene
 	}
@@ -236,6 +252,7 @@ func (refgenes Refgenes) ToSnMap() (refgeneMap map[string]Refgene) {
 
 // ToChromMap 转为chrom染色体为Key的Map集合
 func (refgenes Refgenes) ToChromMap() (refgeneMap map[string]Refgenes) {
+	refgeneMap = make(map[string]Refgenes)
 	for _, refgene := range refgenes {
 		if rgs, ok := refgeneMap[refgene.Chrom]; ok {
 			refgeneMap[refgene.Chrom] = append(rgs, refgene)
@@ -246,6 +263,13 @@ func (refgenes Refgenes) ToChromMap() (refgeneMap map[string]Refgenes) {
 	return
 }
 
+// SetEntrezid 向Refgenes中添加Entrez ID
+func (refgenes *Refgenes) SetEntrezid(ncbiGene NcbiGene) {
+	for i := range *refgenes {
+		(*refgenes)[i].EntrezID = ncbiGene.GetEntrezID((*refgenes)[i].Gene)
+	}
+}
+
 // SetEntrezidAndSequence 向Refgenes中添加Entrez ID和Sequence信息
 func (refgenes *Refgenes) SetEntrezidAndSequence(ncbiGene NcbiGene, mrna Fasta) {
 	log.Printf("start set entrez id and sequence to refgenes")
@@ -258,6 +282,24 @@ func (refgenes *Refgenes) SetEntrezidAndSequence(ncbiGene NcbiGene, mrna Fasta)
 	}
 }
 
+// SetEntrezidAndReference 向Refgenes中添加Entrez ID，并从参考基因组读取序列设置cDNA及蛋白序列(不保留mRNA序列)
+func (refgenes *Refgenes) SetEntrezidAndReference(ncbiGene NcbiGene, reference *Faidx) error {
+	log.Printf("start set entrez id and sequence to refgenes")
+	for i, refgene := range *refgenes {
+		refgene.EntrezID = ncbiGene.GetEntrezID(refgene.Gene)
+		if _, ok := reference.GetLength(refgene.Chrom); ok {
+			mrna, err := reference.GetSeq(refgene.Chrom, refgene.ExonStart, refgene.ExonEnd)
+			if err != nil {
+				return err
+			}
+			refgene.SetSequence(mrna)
+			refgene.Mrna.Clear()
+		}
+		(*refgenes)[i] = refgene
+	}
+	return nil
+}
+
 func (refgenes Refgenes) Len() int {
 	return len(refgenes)
 }
@@ -275,61 +317,80 @@ func (refgenes Refgenes) Swap(i, j int) {
 	refgenes[i], refgenes[j] = refgenes[j], refgenes[i]
 }
 
-// ReadRefgeneFiles 读取Refgene文件
-func ReadRefgeneFiles(refgeneFiles []string, refgenesChan chan Refgenes) {
+// ReadRefgeneFile 读取UCSC RefGene格式文件
+func ReadRefgeneFile(refgeneFile string) (refgenes Refgenes, err error) {
+	lines, err := ReadFile(refgeneFile)
+	if err != nil {
+		return
+	}
+	for _, line := range lines {
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 || line[0] == '#' {
+			continue
+		}
+		var refgene Refgene
+		if refgene, err = NewRefgene(string(line)); err != nil {
+			return
+		}
+		refgenes = append(refgenes, refgene)
+	}
+	return
+}
+
+// ReadRefgeneFiles 读取转录本文件，支持UCSC RefGene、GTF和GFF3格式；只保留当前基因组版本中的染色体，
+// mtFile不为空时线粒体转录本只从mtFile读取
+func ReadRefgeneFiles(refgeneFiles []string, mtFile string) (refgenes Refgenes, err error) {
 	log.Printf("start read %s\n", strings.Join(refgeneFiles, ","))
-	refgenes := make(Refgenes, 0)
+	refgenes = make(Refgenes, 0)
 	for _, refgeneFile := range refgeneFiles {
-		var fp *os.File
-		fp, err := os.Open(refgeneFile)
+		var fileRefgenes Refgenes
+		switch GetRefgeneFormat(refgeneFile) {
+		case "gtf":
+			fileRefgenes, err = ReadGtfFile(refgeneFile)
+		case "gff3":
+			fileRefgenes, err = ReadGff3File(refgeneFile)
+		default:
+			fileRefgenes, err = ReadRefgeneFile(refgeneFile)
+		}
 		if err != nil {
-			log.Fatal(err)
+			return
 		}
-		defer fp.Close()
-		reader := bufio.NewReader(fp)
-		for {
-			var line string
-			line, err = reader.ReadString('\n')
-			if err != nil {
-				if err == io.EOF {
-					break
-				} else {
-					log.Fatal(err)
-				}
-			}
-			line = strings.TrimSpace(line)
-			if len(line) == 0 || line[0] == '#' {
+		unknown := make(UnknownChroms)
+		for _, refgene := range fileRefgenes {
+			if !IsKnownChrom(refgene.Chrom) {
+				unknown[refgene.Chrom]++
 				continue
 			}
-			var refgene Refgene
-			refgene, err = NewRefgene(line)
-			if err != nil {
-				log.Fatal(err)
-			}
-			if refgene.Chrom == "M" || len(refgene.Chrom) > 2 {
+			if mtFile != "" && refgeneFile != mtFile && IsMitochondrion(refgene.Chrom) {
 				continue
 			}
 			refgenes = append(refgenes, refgene)
 		}
+		unknown.Report(refgeneFile)
 	}
 	sort.Sort(refgenes)
-	refgenesChan <- refgenes
+	return
 }
 
-// WriteMrnaFile 根据reference和refgeneMap输出mRNA序列Fasta文件
-func WriteMrnaFile(mrnaFile string, refgenes Refgenes, reference Fasta) {
+// WriteMrnaFile 根据reference(按区间随机读取)和refgenes输出mRNA序列Fasta文件
+func WriteMrnaFile(mrnaFile string, refgenes Refgenes, reference *Faidx) error {
 	log.Printf("start write %s\n", mrnaFile)
 	fp, err := os.Create(mrnaFile)
-	if err == nil {
-		log.Fatal(err)
+	if err != nil {
+		return err
 	}
 	defer fp.Close()
+	writer := bufio.NewWriter(fp)
 	for _, refgene := range refgenes {
-		if chromSeq, ok := reference[refgene.Chrom]; ok {
-			mrna := chromSeq.GetSeq(refgene.ExonStart-1, refgene.ExonEnd-refgene.ExonStart+1)
-			if _, err := fp.WriteString(fmt.Sprintf(">%s\n%s\n", refgene.GetSn(), mrna)); err != nil {
-				log.Fatal(err)
+		if _, ok := reference.GetLength(refgene.Chrom); ok {
+			mrna, err := reference.GetSeq(refgene.Chrom, refgene.ExonStart, refgene.ExonEnd)
+			if err != nil {
+				return err
+			}
+			if _, err := writer.WriteString(fmt.Sprintf(">%s\n%s\n", refgene.GetSn(), mrna)); err != nil {
+				return err
 			}
 		}
 	}
+	return writer.Flush()
 }
@@ -0,0 +1,96 @@
+package data
+
+import (
+	"sort"
+)
+
+// refgeneTree 单条染色体的转录本区间树：按起始位置排序的数组隐式构成平衡二叉树，节点记录子树最大终止位置
+type refgeneTree struct {
+	refgenes Refgenes
+	starts   []int
+	ends     []int
+	maxEnds  []int
+}
+
+// build 构建区间[lo, hi)的子树，返回子树最大终止位置
+func (tree *refgeneTree) build(lo int, hi int) int {
+	if lo >= hi {
+		return -1
+	}
+	mid := (lo + hi) / 2
+	maxEnd := tree.ends[mid]
+	if leftMax := tree.build(lo, mid); leftMax > maxEnd {
+		maxEnd = leftMax
+	}
+	if rightMax := tree.build(mid+1, hi); rightMax > maxEnd {
+		maxEnd = rightMax
+	}
+	tree.maxEnds[mid] = maxEnd
+	return maxEnd
+}
+
+// query 查询子树[lo, hi)中与区间[start, end]重叠的转录本
+func (tree *refgeneTree) query(lo int, hi int, start int, end int, refgenes *Refgenes) {
+	if lo >= hi {
+		return
+	}
+	mid := (lo + hi) / 2
+	if tree.maxEnds[mid] < start {
+		return
+	}
+	tree.query(lo, mid, start, end, refgenes)
+	if tree.starts[mid] > end {
+		return
+	}
+	if tree.ends[mid] >= start {
+		*refgenes = append(*refgenes, tree.refgenes[mid])
+	}
+	tree.query(mid+1, hi, start, end, refgenes)
+}
+
+// RefgeneIndex 转录本区间树索引(包括上下游区域)，以染色体为Key
+type RefgeneIndex map[string]*refgeneTree
+
+// GetSpan 获取Refgene包括上下游区域的范围
+func (refgene Refgene) GetSpan() (start int, end int) {
+	start, end = refgene.ExonStart, refgene.ExonEnd
+	if len(refgene.Streams) == 2 {
+		start, end = refgene.Streams[0].Start, refgene.Streams[1].End
+	}
+	return
+}
+
+// NewRefgeneIndex 创建转录本区间树索引
+func NewRefgeneIndex(refgenes Refgenes) RefgeneIndex {
+	index := make(RefgeneIndex)
+	for chrom, chromRefgenes := range refgenes.ToChromMap() {
+		sorted := make(Refgenes, len(chromRefgenes))
+		copy(sorted, chromRefgenes)
+		sort.SliceStable(sorted, func(i, j int) bool {
+			starti, _ := sorted[i].GetSpan()
+			startj, _ := sorted[j].GetSpan()
+			return starti < startj
+		})
+		tree := &refgeneTree{
+			refgenes: sorted,
+			starts:   make([]int, len(sorted)),
+			ends:     make([]int, len(sorted)),
+			maxEnds:  make([]int, len(sorted)),
+		}
+		for i, refgene := range sorted {
+			tree.starts[i], tree.ends[i] = refgene.GetSpan()
+		}
+		tree.build(0, len(sorted))
+		index[chrom] = tree
+	}
+	return index
+}
+
+// FindRefgenes 获取与区间[start, end]重叠的转录本，按起始位置排序
+func (index RefgeneIndex) FindRefgenes(chrom string, start int, end int) Refgenes {
+	var refgenes Refgenes
+	if tree, ok := index[chrom]; ok {
+		tree.query(0, len(tree.refgenes), start, end, &refgenes)
+	}
+	return refgenes
+}
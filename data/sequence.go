@@ -37,6 +37,25 @@ func (seq *Sequence) Reverse() {
 	*seq = Sequence(buffer.String())
 }
 
+// complements 互补碱基
+var complements = map[Base]Base{
+	'A': 'T', 'T': 'A', 'C': 'G', 'G': 'C', 'N': 'N',
+	'a': 't', 't': 'a', 'c': 'g', 'g': 'c', 'n': 'n',
+}
+
+// ReverseComplement 反向互补序列，未知碱基保持不变
+func (seq *Sequence) ReverseComplement() {
+	var buffer bytes.Buffer
+	for i := seq.GetLen() - 1; i >= 0; i-- {
+		base := seq.GetChar(i)
+		if complement, ok := complements[base]; ok {
+			base = complement
+		}
+		buffer.WriteByte(base)
+	}
+	*seq = Sequence(buffer.String())
+}
+
 // GetLen 获取序列长度
 func (seq Sequence) GetLen() int {
 	return len(seq)
@@ -160,12 +179,12 @@ func (seq Sequence) GetOne2Tree() string {
 }
 
 // ReadFastaFile 读取Fasta文件
-func ReadFastaFile(fastaFile string, fastaChan chan Fasta) {
+func ReadFastaFile(fastaFile string) (fasta Fasta, err error) {
 	log.Printf("start read %s\n", fastaFile)
-	fasta := make(Fasta, 0)
+	fasta = make(Fasta, 0)
 	fp, err := os.Open(fastaFile)
-	if err == nil {
-		log.Fatal(err)
+	if err != nil {
+		return
 	}
 	defer fp.Close()
 	reader := bufio.NewReader(fp)
@@ -174,10 +193,12 @@ func ReadFastaFile(fastaFile string, fastaChan chan Fasta) {
 		var line []byte
 		line, err = reader.ReadBytes('\n')
 		if err != nil {
-			if err == io.EOF {
+			if err != io.EOF {
+				return
+			}
+			err = nil
+			if len(line) == 0 {
 				break
-			} else {
-				log.Fatal(err)
 			}
 		}
 		line = bytes.TrimSpace(line)
@@ -198,5 +219,5 @@ func ReadFastaFile(fastaFile string, fastaChan chan Fasta) {
 	if name.Len() != 0 {
 		fasta[strings.Split(name.String(), " ")[0]] = Sequence(seq.String())
 	}
-	fastaChan <- fasta
+	return
 }
@@ -0,0 +1,76 @@
+package data
+
+import (
+	"fmt"
+	"strings"
+)
+
+// 样本性别
+const (
+	SexMale    = "male"
+	SexFemale  = "female"
+	SexUnknown = "unknown"
+)
+
+// ParseSex 解析性别：M/male/1为男性，F/female/2为女性，其他为未知
+func ParseSex(value string) string {
+	switch strings.ToLower(value) {
+	case "m", "male", "1":
+		return SexMale
+	case "f", "female", "2":
+		return SexFemale
+	default:
+		return SexUnknown
+	}
+}
+
+// ParseSexes 解析以逗号分隔的"样本=性别"列表
+func ParseSexes(value string) (map[string]string, error) {
+	sexes := make(map[string]string)
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item == "" {
+			continue
+		}
+		field := strings.SplitN(item, "=", 2)
+		if len(field) != 2 || field[0] == "" {
+			return nil, fmt.Errorf("invalid sample sex: %s", item)
+		}
+		sexes[field[0]] = ParseSex(field[1])
+	}
+	return sexes, nil
+}
+
+// GetSexChrom 获取染色体对应的性染色体(X/Y)，不是性染色体时返回空字符串
+func GetSexChrom(chrom string) string {
+	name := strings.TrimPrefix(ConvertChrom(chrom), "chr")
+	if name == "X" || name == "Y" {
+		return name
+	}
+	return ""
+}
+
+// IsPar 位置是否位于拟常染色体区(PAR)
+func IsPar(chrom string, pos int) bool {
+	chrom = ConvertChrom(chrom)
+	for _, par := range Config.Par {
+		if ConvertChrom(par.Chrom) == chrom && par.Start <= pos && pos <= par.End {
+			return true
+		}
+	}
+	return false
+}
+
+// GetPloidy 获取性别为sex的样本在位置上的倍性：男性X/Y的非PAR区为1，女性Y为0，其他为2(包括性别未知)
+func GetPloidy(chrom string, pos int, sex string) int {
+	sexChrom := GetSexChrom(chrom)
+	if sexChrom == "" || sex == SexUnknown || sex == "" || IsPar(chrom, pos) {
+		return 2
+	}
+	if sexChrom == "Y" && sex == SexFemale {
+		return 0
+	}
+	if sex == SexMale {
+		return 1
+	}
+	return 2
+}
@@ -0,0 +1,160 @@
+package data
+
+import (
+	"compress/gzip"
+	"encoding/binary"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+)
+
+// tabixChunk Tabix索引块：BGZF虚拟偏移区间
+type tabixChunk struct {
+	Begin uint64
+	End   uint64
+}
+
+// tabixIndex 单条序列的Tabix索引
+type tabixIndex struct {
+	Bins    map[uint32][]tabixChunk
+	Offsets []uint64
+}
+
+// Tabix Tabix(.tbi)索引
+type Tabix struct {
+	Names   map[string]int
+	Indexes []tabixIndex
+}
+
+// ReadTabixFile 读取Tabix(.tbi)索引文件
+func ReadTabixFile(tbiFile string) (*Tabix, error) {
+	fp, err := os.Open(tbiFile)
+	if err != nil {
+		return nil, err
+	}
+	defer fp.Close()
+	reader, err := gzip.NewReader(fp)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	magic := make([]byte, 4)
+	if _, err := io.ReadFull(reader, magic); err != nil {
+		return nil, err
+	}
+	if string(magic) != "TBI\x01" {
+		return nil, errors.New("invalid tabix file: " + tbiFile)
+	}
+	var header [8]int32
+	if err := binary.Read(reader, binary.LittleEndian, &header); err != nil {
+		return nil, err
+	}
+	nRef, lenName := int(header[0]), int(header[7])
+	names := make([]byte, lenName)
+	if _, err := io.ReadFull(reader, names); err != nil {
+		return nil, err
+	}
+	tabix := &Tabix{Names: make(map[string]int), Indexes: make([]tabixIndex, nRef)}
+	for i, name := range strings.Split(strings.TrimRight(string(names), "\x00"), "\x00") {
+		tabix.Names[name] = i
+	}
+	for i := 0; i < nRef; i++ {
+		index := tabixIndex{Bins: make(map[uint32][]tabixChunk)}
+		var nBin int32
+		if err := binary.Read(reader, binary.LittleEndian, &nBin); err != nil {
+			return nil, err
+		}
+		for j := 0; j < int(nBin); j++ {
+			var bin uint32
+			var nChunk int32
+			if err := binary.Read(reader, binary.LittleEndian, &bin); err != nil {
+				return nil, err
+			}
+			if err := binary.Read(reader, binary.LittleEndian, &nChunk); err != nil {
+				return nil, err
+			}
+			chunks := make([]tabixChunk, nChunk)
+			if err := binary.Read(reader, binary.LittleEndian, chunks); err != nil {
+				return nil, err
+			}
+			index.Bins[bin] = chunks
+		}
+		var nIntv int32
+		if err := binary.Read(reader, binary.LittleEndian, &nIntv); err != nil {
+			return nil, err
+		}
+		index.Offsets = make([]uint64, nIntv)
+		if err := binary.Read(reader, binary.LittleEndian, index.Offsets); err != nil {
+			return nil, err
+		}
+		tabix.Indexes[i] = index
+	}
+	return tabix, nil
+}
+
+// getTabixBins 获取与区间[begin, end)重叠的所有bin(UCSC binning scheme)
+func getTabixBins(begin int, end int) []uint32 {
+	end--
+	bins := []uint32{0}
+	for _, level := range []struct {
+		offset int
+		shift  uint
+	}{{1, 26}, {9, 23}, {73, 20}, {585, 17}, {4681, 14}} {
+		for k := level.offset + begin>>level.shift; k <= level.offset+end>>level.shift; k++ {
+			bins = append(bins, uint32(k))
+		}
+	}
+	return bins
+}
+
+// GetOffset 获取与区间[start, end](1-based)重叠的第一条记录的BGZF虚拟偏移
+func (tabix *Tabix) GetOffset(chrom string, start int, end int) (offset uint64, ok bool) {
+	tid, ok := tabix.Names[chrom]
+	if !ok {
+		return 0, false
+	}
+	index := tabix.Indexes[tid]
+	begin := start - 1
+	if begin < 0 {
+		begin = 0
+	}
+	var minOffset uint64
+	if len(index.Offsets) > 0 {
+		window := begin >> 14
+		if window >= len(index.Offsets) {
+			window = len(index.Offsets) - 1
+		}
+		minOffset = index.Offsets[window]
+	}
+	ok = false
+	for _, bin := range getTabixBins(begin, end) {
+		for _, chunk := range index.Bins[bin] {
+			if chunk.End <= minOffset {
+				continue
+			}
+			if !ok || chunk.Begin < offset {
+				offset, ok = chunk.Begin, true
+			}
+		}
+	}
+	if ok && offset < minOffset {
+		offset = minOffset
+	}
+	return
+}
+
+// OpenBgzfReader 从BGZF虚拟偏移处打开解压读取流
+func OpenBgzfReader(fp *os.File, offset uint64) (io.ReadCloser, error) {
+	section := io.NewSectionReader(fp, int64(offset>>16), 1<<62)
+	reader, err := gzip.NewReader(section)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := io.CopyN(ioutil.Discard, reader, int64(offset&0xffff)); err != nil {
+		reader.Close()
+		return nil, err
+	}
+	return reader, nil
+}
@@ -5,7 +5,7 @@ import (
 	"bytes"
 	"compress/gzip"
 	"encoding/json"
-	"errors"
+	"io"
 	"io/ioutil"
 	"os"
 	"strconv"
@@ -20,14 +20,16 @@ func GetChromNames() (chromList []string) {
 	return
 }
 
-// GetChromByName 通过名称获取染色体信息
+// GetChromByName 通过名称或别名获取染色体信息，不在当前基因组版本中的染色体排在最后且长度为0，
+// 按首次出现的顺序分别排序
 func GetChromByName(name string) (order int, length int) {
+	name = ConvertChrom(name)
 	for index, chrom := range Config.Chrom {
 		if chrom.Name == name {
 			return index + 1, chrom.Length
 		}
 	}
-	panic(errors.New("Not Found: " + name))
+	return getExtraChromOrder(name), 0
 }
 
 // ReadFile 读取文件全部内容
@@ -93,3 +95,32 @@ func ConvertToJSON(data interface{}) (string, error) {
 	}
 	return buffer.String(), nil
 }
+
+// gzipFile gzip压缩文件
+type gzipFile struct {
+	*gzip.Reader
+	fp *os.File
+}
+
+// Close 关闭gzip流及文件
+func (file gzipFile) Close() error {
+	file.Reader.Close()
+	return file.fp.Close()
+}
+
+// OpenFile 打开文件，后缀为.gz时自动解压
+func OpenFile(file string) (io.ReadCloser, error) {
+	fp, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	if strings.HasSuffix(strings.ToLower(file), ".gz") {
+		reader, err := gzip.NewReader(fp)
+		if err != nil {
+			fp.Close()
+			return nil, err
+		}
+		return gzipFile{Reader: reader, fp: fp}, nil
+	}
+	return fp, nil
+}
@@ -28,9 +28,7 @@ func (variant Variant) GetNumericalPosition() (int, int) {
 
 // ConvertSnv 标准化变异信息
 func (variant *Variant) ConvertSnv() {
-	if variant.Chrom == "M" {
-		variant.Chrom = "MT"
-	}
+	variant.Chrom = ConvertChrom(variant.Chrom)
 	if !variant.Ref.IsEmpty() || !variant.Alt.IsEmpty() && !variant.Ref.IsEqual(variant.Alt) {
 		if variant.Ref.IsStartswith(variant.Alt) || variant.Ref.IsEndswith(variant.Alt) {
 			if variant.Ref.IsStartswith(variant.Alt) {
@@ -54,7 +52,7 @@ func (variant *Variant) ConvertSnv() {
 			altRev = variant.Alt
 			refRev.Reverse()
 			altRev.Reverse()
-			for i, subLen := 0, 0; i < variant.Ref.GetLen() && i < variant.Alt.GetLen(); i++ {
+			for i := 0; i < variant.Ref.GetLen() && i < variant.Alt.GetLen(); i++ {
 				if refRev.GetChar(i) != altRev.GetChar(i) {
 					break
 				}
@@ -62,7 +60,8 @@ func (variant *Variant) ConvertSnv() {
 			}
 			variant.Ref = variant.Ref.GetSeq(0, variant.Ref.GetLen()-subLen)
 			variant.Alt = variant.Alt.GetSeq(0, variant.Alt.GetLen()-subLen)
-			for i, subLen := 0, 0; i < variant.Ref.GetLen() && i < variant.Alt.GetLen(); i++ {
+			subLen = 0
+			for i := 0; i < variant.Ref.GetLen() && i < variant.Alt.GetLen(); i++ {
 				if variant.Ref.GetChar(i) != variant.Alt.GetChar(i) {
 					break
 				}
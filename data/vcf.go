@@ -0,0 +1,122 @@
+package data
+
+import (
+	"bufio"
+	"io"
+	"strings"
+)
+
+// VcfAnnoKey VCF输出时注释信息所在的INFO字段名
+const VcfAnnoKey = "GRANDANNO"
+
+// VcfAnnoFields VCF注释信息字段，以"|"分隔
+var VcfAnnoFields = []string{"Allele", "Gene", "EntrezID", "Transcript", "Exon", "NaChange", "AaChange", "Region", "Function", "Consequence", "Impact"}
+
+// GetVcfAnnoHeader 获取注释信息的INFO表头
+func GetVcfAnnoHeader() string {
+	return `##INFO=<ID=` + VcfAnnoKey + `,Number=.,Type=String,Description="Functional annotations from GrandAnno. Format: ` +
+		strings.Join(VcfAnnoFields, "|") + `">`
+}
+
+// ReadVcfHeader 读取VCF文件的表头(以#开头的行)
+func ReadVcfHeader(vcfFile string) (header []string, err error) {
+	fp, err := OpenFile(vcfFile)
+	if err != nil {
+		return
+	}
+	defer fp.Close()
+	reader := bufio.NewReader(fp)
+	for {
+		var line string
+		line, err = reader.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return
+		}
+		if !strings.HasPrefix(line, "#") {
+			return header, nil
+		}
+		header = append(header, strings.TrimRight(line, "\r\n"))
+		if err == io.EOF {
+			return header, nil
+		}
+	}
+}
+
+// VcfNormKey VCF输出时左对齐后的变异所在的INFO字段名
+const VcfNormKey = "GRANDANNO_NORM"
+
+// GetVcfNormHeader 获取左对齐后变异的INFO表头
+func GetVcfNormHeader() string {
+	return `##INFO=<ID=` + VcfNormKey + `,Number=A,Type=String,Description="Left-aligned representation of each ALT from GrandAnno. Format: chrom:start:end:ref:alt">`
+}
+
+// VcfInheritanceKey VCF输出时样本遗传方式所在的INFO字段名
+const VcfInheritanceKey = "GRANDANNO_INH"
+
+// GetVcfInheritanceHeader 获取样本遗传方式的INFO表头
+func GetVcfInheritanceHeader() string {
+	return `##INFO=<ID=` + VcfInheritanceKey + `,Number=.,Type=String,Description="Inheritance of ALT alleles in samples whose parents are in the PED file from GrandAnno. Format: Allele|Sample|Inheritance(&-separated)">`
+}
+
+// VcfOffPanelKey VCF输出时标记不在panel中变异的INFO字段名
+const VcfOffPanelKey = "GRANDANNO_OFF_PANEL"
+
+// GetVcfOffPanelHeader 获取不在panel中变异的INFO表头
+func GetVcfOffPanelHeader() string {
+	return `##INFO=<ID=` + VcfOffPanelKey + `,Number=0,Type=Flag,Description="Variant is outside the gene panel or regions given to GrandAnno">`
+}
+
+// InsertVcfHeader 在#CHROM行之前插入新的表头行
+func InsertVcfHeader(header []string, lines ...string) []string {
+	newHeader := make([]string, 0, len(header)+len(lines))
+	inserted := false
+	for _, line := range header {
+		if !inserted && strings.HasPrefix(line, "#CHROM") {
+			newHeader = append(newHeader, lines...)
+			inserted = true
+		}
+		newHeader = append(newHeader, line)
+	}
+	if !inserted {
+		newHeader = append(newHeader, lines...)
+	}
+	return newHeader
+}
+
+// EscapeVcfInfo 转义INFO值中不允许出现的字符
+func EscapeVcfInfo(value string) string {
+	return strings.NewReplacer("%", "%25", ";", "%3B", "=", "%3D", ",", "%2C", " ", "%20", "|", "%7C", "\t", "%09").Replace(value)
+}
+
+// AddVcfInfo 向VCF行的INFO列添加字段
+func AddVcfInfo(vcfLine string, key string, value string) string {
+	return addVcfInfo(vcfLine, key+"="+value)
+}
+
+// AddVcfFlag 向VCF行的INFO列添加Flag字段
+func AddVcfFlag(vcfLine string, key string) string {
+	return addVcfInfo(vcfLine, key)
+}
+
+// addVcfInfo 向VCF行的INFO列添加一项
+func addVcfInfo(vcfLine string, info string) string {
+	field := strings.Split(vcfLine, "\t")
+	if len(field) < 8 {
+		return vcfLine
+	}
+	if field[7] != "" && field[7] != "." {
+		info = field[7] + ";" + info
+	}
+	field[7] = info
+	return strings.Join(field, "\t")
+}
+
+// SetVcfID 设置VCF行的ID列，原ID列不为空时保留原ID
+func SetVcfID(vcfLine string, id string) string {
+	field := strings.Split(vcfLine, "\t")
+	if len(field) < 3 || field[2] != "." && field[2] != "" {
+		return vcfLine
+	}
+	field[2] = id
+	return strings.Join(field, "\t")
+}
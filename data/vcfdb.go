@@ -0,0 +1,256 @@
+package data
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// VcfRecord 数据库VCF记录
+type VcfRecord struct {
+	Chrom string
+	Pos   int
+	ID    string
+	Ref   string
+	Alts  []string
+	Info  string
+}
+
+// NewVcfRecord 读取一行VCF Line, 创建VcfRecord
+func NewVcfRecord(vcfLine string) (record VcfRecord, err error) {
+	field := strings.SplitN(vcfLine, "\t", 9)
+	if len(field) < 8 {
+		err = io.ErrUnexpectedEOF
+		return
+	}
+	record = VcfRecord{
+		Chrom: field[0],
+		ID:    field[2],
+		Ref:   field[3],
+		Alts:  strings.Split(field[4], ","),
+		Info:  field[7],
+	}
+	record.Pos, err = strconv.Atoi(field[1])
+	return
+}
+
+// GetEnd 获取记录的终止位置
+func (record VcfRecord) GetEnd() int {
+	return record.Pos + len(record.Ref) - 1
+}
+
+// GetInfo 获取INFO字段的值，Flag类型的字段返回"true"
+func (record VcfRecord) GetInfo(key string) (string, bool) {
+	for _, info := range strings.Split(record.Info, ";") {
+		if info == key {
+			return "true", true
+		}
+		if strings.HasPrefix(info, key+"=") {
+			return info[len(key)+1:], true
+		}
+	}
+	return "", false
+}
+
+// GetInfoOfAlt 获取INFO字段中指定ALT的值(Number=A)
+func (record VcfRecord) GetInfoOfAlt(key string, altIndex int) (string, bool) {
+	value, ok := record.GetInfo(key)
+	if !ok {
+		return "", false
+	}
+	values := strings.Split(value, ",")
+	if altIndex >= len(values) {
+		return "", false
+	}
+	return values[altIndex], true
+}
+
+// GetVariant 获取指定ALT的标准化变异信息
+func (record VcfRecord) GetVariant(altIndex int) Variant {
+	variant := Variant{
+		Chrom: record.Chrom,
+		Start: record.Pos,
+		Ref:   Sequence(record.Ref),
+		Alt:   Sequence(record.Alts[altIndex]),
+	}
+	variant.ConvertSnv()
+	return variant
+}
+
+// VcfMatch 与变异匹配的数据库VCF记录及ALT下标
+type VcfMatch struct {
+	Record   VcfRecord
+	AltIndex int
+}
+
+// VcfDB 本地VCF数据库，bgzip压缩且存在Tabix索引时按区间随机读取，否则全部读入内存
+type VcfDB struct {
+	File    string
+	fp      *os.File
+	tabix   *Tabix
+	records map[string][]VcfRecord
+	maxLen  int
+}
+
+// OpenVcfDB 打开本地VCF数据库
+func OpenVcfDB(vcfFile string) (*VcfDB, error) {
+	db := &VcfDB{File: vcfFile}
+	if _, err := os.Stat(vcfFile + ".tbi"); err == nil {
+		if db.tabix, err = ReadTabixFile(vcfFile + ".tbi"); err != nil {
+			return nil, err
+		}
+		if db.fp, err = os.Open(vcfFile); err != nil {
+			return nil, err
+		}
+		return db, nil
+	}
+	log.Printf("start read %s\n", vcfFile)
+	db.records = make(map[string][]VcfRecord)
+	fp, err := OpenFile(vcfFile)
+	if err != nil {
+		return nil, err
+	}
+	defer fp.Close()
+	reader := bufio.NewReader(fp)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+		line = bytes.TrimSpace(line)
+		if len(line) > 0 && line[0] != '#' {
+			record, e := NewVcfRecord(string(line))
+			if e != nil {
+				return nil, e
+			}
+			db.records[record.Chrom] = append(db.records[record.Chrom], record)
+			if len(record.Ref) > db.maxLen {
+				db.maxLen = len(record.Ref)
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+	}
+	for _, records := range db.records {
+		sort.SliceStable(records, func(i, j int) bool { return records[i].Pos < records[j].Pos })
+	}
+	return db, nil
+}
+
+// Close 关闭数据库文件
+func (db *VcfDB) Close() error {
+	if db.fp != nil {
+		return db.fp.Close()
+	}
+	return nil
+}
+
+// hasChrom 数据库中是否存在该染色体
+func (db *VcfDB) hasChrom(chrom string) bool {
+	if db.tabix != nil {
+		_, ok := db.tabix.Names[chrom]
+		return ok
+	}
+	_, ok := db.records[chrom]
+	return ok
+}
+
+// resolveChrom 获取数据库中的染色体名称(兼容染色体别名)
+func (db *VcfDB) resolveChrom(chrom string) (string, bool) {
+	if db.hasChrom(chrom) {
+		return chrom, true
+	}
+	for _, candidate := range GetChromAliases(chrom) {
+		if db.hasChrom(candidate) {
+			return candidate, true
+		}
+	}
+	return "", false
+}
+
+// Query 获取与区间[start, end]重叠的所有记录
+func (db *VcfDB) Query(chrom string, start int, end int) (records []VcfRecord, err error) {
+	chrom, ok := db.resolveChrom(chrom)
+	if !ok {
+		return
+	}
+	if db.tabix == nil {
+		chromRecords := db.records[chrom]
+		index := sort.Search(len(chromRecords), func(i int) bool { return chromRecords[i].Pos > end })
+		for i := index - 1; i >= 0; i-- {
+			if chromRecords[i].GetEnd() >= start {
+				records = append(records, chromRecords[i])
+			} else if chromRecords[i].Pos+db.maxLen <= start {
+				break
+			}
+		}
+		for i, j := 0, len(records)-1; i < j; i, j = i+1, j-1 {
+			records[i], records[j] = records[j], records[i]
+		}
+		return
+	}
+	offset, ok := db.tabix.GetOffset(chrom, start, end)
+	if !ok {
+		return
+	}
+	reader, err := OpenBgzfReader(db.fp, offset)
+	if err != nil {
+		return
+	}
+	defer reader.Close()
+	bufReader := bufio.NewReader(reader)
+	for {
+		var line []byte
+		line, err = bufReader.ReadBytes('\n')
+		if err != nil && err != io.EOF {
+			return
+		}
+		eof := err == io.EOF
+		err = nil
+		line = bytes.TrimSpace(line)
+		if len(line) > 0 && line[0] != '#' {
+			var record VcfRecord
+			if record, err = NewVcfRecord(string(line)); err != nil {
+				return
+			}
+			if record.Chrom != chrom || record.Pos > end {
+				break
+			}
+			if record.GetEnd() >= start {
+				records = append(records, record)
+			}
+		}
+		if eof {
+			break
+		}
+	}
+	return
+}
+
+// FindVariant 获取与标准化变异完全一致的记录
+func (db *VcfDB) FindVariant(variant Variant) (matches []VcfMatch, err error) {
+	records, err := db.Query(variant.Chrom, variant.Start-1, variant.End)
+	if err != nil {
+		return
+	}
+	sn := variant.GetSn()
+	for _, record := range records {
+		for i, alt := range record.Alts {
+			if alt == "*" || alt == "." || strings.HasPrefix(alt, "<") {
+				continue
+			}
+			recordVariant := record.GetVariant(i)
+			recordVariant.Chrom = variant.Chrom
+			if recordVariant.GetSn() == sn {
+				matches = append(matches, VcfMatch{Record: record, AltIndex: i})
+			}
+		}
+	}
+	return
+}
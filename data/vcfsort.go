@@ -0,0 +1,200 @@
+package data
+
+import (
+	"bufio"
+	"container/heap"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// VcfSortChunkSize 外部排序时每个临时文件包含的最大记录数
+var VcfSortChunkSize = 500000
+
+// vcfSortKey VCF记录的排序依据：染色体按配置文件顺序，未配置的染色体按名称排在最后
+type vcfSortKey struct {
+	order int
+	chrom string
+	pos   int
+}
+
+// less 比较两个排序依据
+func (key vcfSortKey) less(other vcfSortKey) bool {
+	if key.order != other.order {
+		return key.order < other.order
+	}
+	if key.chrom != other.chrom {
+		return key.chrom < other.chrom
+	}
+	return key.pos < other.pos
+}
+
+// getVcfSortKey 获取VCF行的排序依据
+func getVcfSortKey(line string, chromOrders map[string]int) vcfSortKey {
+	field := strings.SplitN(line, "\t", 3)
+	key := vcfSortKey{order: len(chromOrders) + 1, chrom: field[0]}
+	if order, ok := chromOrders[ConvertChrom(field[0])]; ok {
+		key.order = order
+	}
+	if len(field) > 1 {
+		key.pos, _ = strconv.Atoi(field[1])
+	}
+	return key
+}
+
+// vcfSortLine 待排序的VCF行
+type vcfSortLine struct {
+	key  vcfSortKey
+	line string
+}
+
+// vcfSortChunk 已排序的临时文件
+type vcfSortChunk struct {
+	fp      *os.File
+	reader  *bufio.Reader
+	current vcfSortLine
+}
+
+// next 读取临时文件的下一行，文件结束时返回false
+func (chunk *vcfSortChunk) next(chromOrders map[string]int) (bool, error) {
+	line, err := chunk.reader.ReadString('\n')
+	if err != nil && err != io.EOF {
+		return false, err
+	}
+	line = strings.TrimRight(line, "\r\n")
+	if line == "" {
+		return false, nil
+	}
+	chunk.current = vcfSortLine{key: getVcfSortKey(line, chromOrders), line: line}
+	return true, nil
+}
+
+// vcfSortHeap 多路归并使用的最小堆
+type vcfSortHeap []*vcfSortChunk
+
+func (h vcfSortHeap) Len() int            { return len(h) }
+func (h vcfSortHeap) Less(i, j int) bool  { return h[i].current.key.less(h[j].current.key) }
+func (h vcfSortHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *vcfSortHeap) Push(x interface{}) { *h = append(*h, x.(*vcfSortChunk)) }
+func (h *vcfSortHeap) Pop() interface{} {
+	old := *h
+	chunk := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return chunk
+}
+
+// writeVcfSortChunk 将排序后的记录写入临时文件
+func writeVcfSortChunk(lines []vcfSortLine) (*vcfSortChunk, error) {
+	sort.SliceStable(lines, func(i, j int) bool { return lines[i].key.less(lines[j].key) })
+	fp, err := ioutil.TempFile("", "grandanno.*.vcf")
+	if err != nil {
+		return nil, err
+	}
+	writer := bufio.NewWriter(fp)
+	for _, line := range lines {
+		if _, err := writer.WriteString(line.line + "\n"); err != nil {
+			fp.Close()
+			os.Remove(fp.Name())
+			return nil, err
+		}
+	}
+	if err := writer.Flush(); err != nil {
+		fp.Close()
+		os.Remove(fp.Name())
+		return nil, err
+	}
+	if _, err := fp.Seek(0, io.SeekStart); err != nil {
+		fp.Close()
+		os.Remove(fp.Name())
+		return nil, err
+	}
+	return &vcfSortChunk{fp: fp, reader: bufio.NewReader(fp)}, nil
+}
+
+// SortVcfFile 对VCF文件按坐标进行外部排序：分块排序写入临时文件后多路归并，输出为未压缩的VCF文件
+func SortVcfFile(vcfFile string, sortedFile string) (err error) {
+	log.Printf("start sort %s\n", vcfFile)
+	chromOrders := make(map[string]int)
+	for i, name := range GetChromNames() {
+		chromOrders[name] = i + 1
+	}
+	fp, err := OpenFile(vcfFile)
+	if err != nil {
+		return
+	}
+	defer fp.Close()
+	fo, err := os.Create(sortedFile)
+	if err != nil {
+		return
+	}
+	defer fo.Close()
+	writer := bufio.NewWriter(fo)
+	var chunks []*vcfSortChunk
+	defer func() {
+		for _, chunk := range chunks {
+			chunk.fp.Close()
+			os.Remove(chunk.fp.Name())
+		}
+	}()
+	var lines []vcfSortLine
+	reader := bufio.NewReader(fp)
+	for {
+		var line string
+		line, err = reader.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return
+		}
+		eof := err == io.EOF
+		err = nil
+		line = strings.TrimRight(line, "\r\n")
+		if strings.HasPrefix(line, "#") {
+			if _, err = writer.WriteString(line + "\n"); err != nil {
+				return
+			}
+		} else if line != "" {
+			lines = append(lines, vcfSortLine{key: getVcfSortKey(line, chromOrders), line: line})
+		}
+		if len(lines) >= VcfSortChunkSize || eof && len(lines) > 0 {
+			var chunk *vcfSortChunk
+			if chunk, err = writeVcfSortChunk(lines); err != nil {
+				return
+			}
+			chunks = append(chunks, chunk)
+			lines = lines[:0]
+		}
+		if eof {
+			break
+		}
+	}
+	h := make(vcfSortHeap, 0, len(chunks))
+	for _, chunk := range chunks {
+		var ok bool
+		if ok, err = chunk.next(chromOrders); err != nil {
+			return
+		}
+		if ok {
+			h = append(h, chunk)
+		}
+	}
+	heap.Init(&h)
+	for h.Len() > 0 {
+		chunk := h[0]
+		if _, err = writer.WriteString(chunk.current.line + "\n"); err != nil {
+			return
+		}
+		var ok bool
+		if ok, err = chunk.next(chromOrders); err != nil {
+			return
+		}
+		if ok {
+			heap.Fix(&h, 0)
+		} else {
+			heap.Pop(&h)
+		}
+	}
+	return writer.Flush()
+}
@@ -1,10 +1,12 @@
 package main
 
 import (
-	"grandanno/cnv"
+	"fmt"
+	"grandanno/annotator"
 	"grandanno/data"
 	"grandanno/snv"
-	"path"
+	"log"
+	"strings"
 
 	"github.com/spf13/cobra"
 )
@@ -16,6 +18,19 @@ var Param struct {
 	Config         string
 	DBPath         string
 	SplicingLength int
+	OutputFormat   string
+	Columns        string
+	Threads        int
+	Phase          bool
+	PerSample      bool
+	CompHet        string
+	Genes          string
+	Regions        string
+	MarkOffPanel   bool
+	Sex            string
+	Ped            string
+	Zygosity       string
+	Build          string
 }
 
 // CorbaCMD 命令行参数解析
@@ -28,23 +43,106 @@ func prepareCMD() *cobra.Command {
 		Short: "预处理",
 		Long:  "对数据库文件进行预处理得到注释所需文件",
 		Run: func(cmd *cobra.Command, args []string) {
-			referenceChan := make(chan data.Fasta, 0)
-			go data.ReadFastaFile(path.Join(Param.DBPath, data.Config.DBFile.Reference), referenceChan)
-			refgenesChan := make(chan data.Refgenes, 0)
-			refgeneFiles := []string{path.Join(Param.DBPath, data.Config.DBFile.Refgene), path.Join(Param.DBPath, data.Config.DBFile.EnsMt)}
-			go data.ReadRefgeneFiles(refgeneFiles, refgenesChan)
-			refidxMapChan := make(chan map[string]data.Refidxs, 0)
-			go data.InitRefidxMap(refidxMapChan)
-			refgenes := <-refgenesChan
-			data.WriteMrnaFile(path.Join(Param.DBPath, data.Config.DBFile.Mrna), refgenes, <-referenceChan)
-			data.WriteRefidxFile(path.Join(Param.DBPath, data.Config.DBFile.Refidx), <-refidxMapChan, refgenes.ToChromMap())
+			if err := annotator.Prepare(Param.DBPath, Param.Config, Param.Build); err != nil {
+				log.Fatal(err)
+			}
+		},
+	}
+	cmd.Flags().StringVarP(&Param.Config, "config", "c", "config.yml", "配置文件")
+	cmd.Flags().StringVarP(&Param.DBPath, "db_path", "d", "humandb", "数据库文件目录")
+	cmd.Flags().StringVarP(&Param.Build, "build", "b", "", "基因组版本，默认使用配置文件中的build")
+	return cmd
+}
+
+// validateCMD 数据库文件检查
+func validateCMD() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "validate",
+		Short: "数据库检查",
+		Long:  "检查数据库文件是否与预处理生成的清单一致，以及mRNA序列与转录本是否一致",
+		Run: func(cmd *cobra.Command, args []string) {
+			problems, err := annotator.Validate(Param.DBPath, Param.Config, Param.Build)
+			if err != nil {
+				log.Fatal(err)
+			}
+			for _, problem := range problems {
+				fmt.Println(problem)
+			}
+			if len(problems) > 0 {
+				log.Fatalf("found %d problems in %s\n", len(problems), Param.DBPath)
+			}
+			log.Printf("%s is valid\n", Param.DBPath)
 		},
 	}
-	cmd.Flags().StringVarP(&Param.DBPath, "config", "c", "config.yml", "配置文件")
+	cmd.Flags().StringVarP(&Param.Config, "config", "c", "config.yml", "配置文件")
 	cmd.Flags().StringVarP(&Param.DBPath, "db_path", "d", "humandb", "数据库文件目录")
+	cmd.Flags().StringVarP(&Param.Build, "build", "b", "", "基因组版本，默认使用配置文件中的build")
 	return cmd
 }
 
+// newAnnotator 根据命令行参数创建注释器
+func newAnnotator() *annotator.Annotator {
+	var genes []string
+	if Param.Genes != "" {
+		var err error
+		if genes, err = data.ReadGeneList(Param.Genes); err != nil {
+			log.Fatal(err)
+		}
+	}
+	anno, err := annotator.NewPanelAnnotator(Param.DBPath, Param.Config, Param.Build, genes, Param.Regions)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if Param.SplicingLength > 0 {
+		anno.SplicingLen = Param.SplicingLength
+	}
+	if Param.Threads > 0 {
+		anno.Threads = Param.Threads
+	}
+	anno.Phase = Param.Phase
+	if Param.Ped != "" {
+		if anno.Pedigree, err = data.ReadPedFile(Param.Ped); err != nil {
+			log.Fatal(err)
+		}
+		anno.Sexes = anno.Pedigree.GetSexes()
+	}
+	if Param.Sex != "" {
+		sexes, err := data.ParseSexes(Param.Sex)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if anno.Sexes == nil {
+			anno.Sexes = sexes
+		}
+		for sample, sex := range sexes {
+			anno.Sexes[sample] = sex
+		}
+	}
+	if Param.Zygosity != "" {
+		anno.Zygosities = strings.Split(Param.Zygosity, ",")
+		for _, zygosity := range anno.Zygosities {
+			if !(snv.GatkSample{Zygosity: zygosity}).HasZygosity(snv.Zygosities) {
+				log.Fatalf("unknown zygosity: %s\n", zygosity)
+			}
+		}
+	}
+	anno.MarkOffPanel = Param.MarkOffPanel
+	anno.Output.Format = Param.OutputFormat
+	anno.Output.PerSample = Param.PerSample
+	anno.Output.CompHetReport = Param.CompHet
+	if Param.Columns != "" {
+		anno.Output.Columns = strings.Split(Param.Columns, ",")
+	}
+	return anno
+}
+
+// addPanelFlags 添加限制注释基因及区域的参数
+func addPanelFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&Param.Genes, "genes", "", "只注释指定基因(Gene symbol或Entrez ID)，以逗号分隔或为基因列表文件")
+	cmd.Flags().StringVar(&Param.Regions, "regions", "", "只注释BED文件中的区域")
+	cmd.Flags().BoolVar(&Param.MarkOffPanel, "mark_off_panel", false, "不在指定基因或区域中的变异标记后输出(不进行基因注释)，默认丢弃")
+}
+
 // annoGATKSNVCMD 注释GATK4 Call SNV的VCF结果文件
 func annoGATKSNVCMD() *cobra.Command {
 	cmd := &cobra.Command{
@@ -52,27 +150,29 @@ func annoGATKSNVCMD() *cobra.Command {
 		Short: "SNV注释(GATK4)",
 		Long:  "注释GATK4 Call SNV的VCF结果文件",
 		Run: func(cmd *cobra.Command, args []string) {
-			ncbiGeneChan := make(chan data.NcbiGene, 0)
-			go data.ReadNCBIGeneInfo(path.Join(Param.DBPath, data.Config.DBFile.NcbiGene), ncbiGeneChan)
-			refgenesChan := make(chan data.Refgenes, 0)
-			refgeneFiles := []string{path.Join(Param.DBPath, data.Config.DBFile.Refgene), path.Join(Param.DBPath, data.Config.DBFile.EnsMt)}
-			go data.ReadRefgeneFiles(refgeneFiles, refgenesChan)
-			mrnaChan := make(chan data.Fasta, 0)
-			go data.ReadFastaFile(path.Join(Param.DBPath, data.Config.DBFile.Mrna), mrnaChan)
-			refidxsChan := make(chan data.Refidxs)
-			go data.ReadRefidxFile(path.Join(Param.DBPath, data.Config.DBFile.Refidx), refidxsChan)
-			refgenes := <-refgenesChan
-			refgenes.SetEntrezidAndSequence(<-ncbiGeneChan, <-mrnaChan)
-			gatkSnvsChan := make(chan snv.Snvs)
-			go snv.ReadGatkVcfFile(Param.Input, gatkSnvsChan)
-			snv.RunAnnotation(<-gatkSnvsChan, refgenes.ToSnMap(), <-refidxsChan, Param.Ouput)
+			anno := newAnnotator()
+			defer anno.Close()
+			if err := anno.AnnotateGatkVcfFile(Param.Input, Param.Ouput); err != nil {
+				log.Fatal(err)
+			}
 		},
 	}
 	cmd.Flags().StringVarP(&Param.Config, "config", "c", "config.yml", "配置文件")
 	cmd.Flags().StringVarP(&Param.DBPath, "db_path", "d", "humandb", "数据库文件目录")
+	cmd.Flags().StringVarP(&Param.Build, "build", "b", "", "基因组版本，默认使用配置文件中的build")
 	cmd.Flags().StringVarP(&Param.Input, "input", "i", "input.vcf", "输入vcf文件")
-	cmd.Flags().StringVarP(&Param.Ouput, "output", "o", "output.json", "输出JSON文件")
+	cmd.Flags().StringVarP(&Param.Ouput, "output", "o", "output.json", "输出文件")
+	cmd.Flags().StringVarP(&Param.OutputFormat, "output-format", "f", annotator.FormatJSON, "输出格式(json/vcf/tsv)")
+	cmd.Flags().StringVar(&Param.Columns, "columns", "", "TSV输出列，以逗号分隔")
+	cmd.Flags().IntVarP(&Param.Threads, "threads", "t", 1, "注释使用的线程数")
+	cmd.Flags().BoolVar(&Param.Phase, "phase", false, "合并注释同一密码子中同相位(PS/PGT/PID)的SNV")
+	cmd.Flags().StringVar(&Param.Sex, "sex", "", "样本性别，以逗号分隔的样本=性别(M/F)，用于判断X/Y非PAR区的半合子")
+	cmd.Flags().StringVar(&Param.Ped, "ped", "", "PED家系文件，用于标记新发/隐性纯合/X连锁/父源/母源变异，并提供样本性别")
+	cmd.Flags().StringVar(&Param.Zygosity, "zygosity", "", "只输出合子状态为指定值的变异，以逗号分隔(het/hom_ref/hom_alt/hemizygous/other_alt/no_call)")
+	cmd.Flags().StringVar(&Param.CompHet, "comphet", "", "复合杂合候选报告输出文件(TSV)，按样本和基因列出HIGH/MODERATE杂合变异对，根据相位或PED判断是否位于不同单倍型")
+	cmd.Flags().BoolVar(&Param.PerSample, "per_sample", false, "每个样本输出一个文件(以输出文件去掉格式后缀作为前缀)，默认所有样本输出到一个文件")
 	cmd.Flags().IntVarP(&Param.SplicingLength, "splicing_len", "s", -1, "预定义的剪接区域长度")
+	addPanelFlags(cmd)
 	return cmd
 }
 
@@ -83,27 +183,22 @@ func annoXHMMCNVCMD() *cobra.Command {
 		Short: "CNV注释(XHMM)",
 		Long:  "注释XHMM Call CNV的VCF结果文件",
 		Run: func(cmd *cobra.Command, args []string) {
-			ncbiGeneChan := make(chan data.NcbiGene, 0)
-			go data.ReadNCBIGeneInfo(path.Join(Param.DBPath, data.Config.DBFile.NcbiGene), ncbiGeneChan)
-			refgenesChan := make(chan data.Refgenes, 0)
-			refgeneFiles := []string{path.Join(Param.DBPath, data.Config.DBFile.Refgene), path.Join(Param.DBPath, data.Config.DBFile.EnsMt)}
-			go data.ReadRefgeneFiles(refgeneFiles, refgenesChan)
-			refidxsChan := make(chan data.Refidxs)
-			go data.ReadRefidxFile(path.Join(Param.DBPath, data.Config.DBFile.Refidx), refidxsChan)
-			xhmmCnvMapChan := make(chan map[string]cnv.Cnvs, 0)
-			go cnv.ReadXhmmVcfFile(Param.Input, xhmmCnvMapChan)
-			refgenes := <-refgenesChan
-			refidxs := <-refidxsChan
-			for sample, cnvs := range <-xhmmCnvMapChan {
-				outJSONFile := path.Join(Param.Ouput + "." + sample + ".json")
-				cnv.RunAnnotation(cnvs, refgenes.ToSnMap(), refidxs, outJSONFile)
+			anno := newAnnotator()
+			defer anno.Close()
+			if err := anno.AnnotateXhmmVcfFile(Param.Input, Param.Ouput); err != nil {
+				log.Fatal(err)
 			}
 		},
 	}
 	cmd.Flags().StringVarP(&Param.Config, "config", "c", "config.yml", "配置文件")
 	cmd.Flags().StringVarP(&Param.DBPath, "db_path", "d", "humandb", "数据库文件目录")
+	cmd.Flags().StringVarP(&Param.Build, "build", "b", "", "基因组版本，默认使用配置文件中的build")
 	cmd.Flags().StringVarP(&Param.Input, "input", "i", "input.vcf", "输入vcf文件")
-	cmd.Flags().StringVarP(&Param.Ouput, "output", "o", "output.json", "输出JSON文件")
+	cmd.Flags().StringVarP(&Param.Ouput, "output", "o", "output", "输出文件前缀")
+	cmd.Flags().StringVarP(&Param.OutputFormat, "output-format", "f", annotator.FormatJSON, "输出格式(json/vcf/tsv)")
+	cmd.Flags().StringVar(&Param.Columns, "columns", "", "TSV输出列，以逗号分隔")
+	cmd.Flags().IntVarP(&Param.Threads, "threads", "t", 1, "注释使用的线程数")
+	addPanelFlags(cmd)
 	return cmd
 }
 
@@ -113,11 +208,7 @@ func init() {
 		Short: "注释",
 		Long:  "变异注释软件",
 	}
-	CorbaCMD.AddCommand(prepareCMD(), annoGATKSNVCMD(), annoXHMMCNVCMD())
-	data.ReadConfigYAML(Param.Config)
-	if Param.SplicingLength > 0 {
-		data.Config.Param.SplicingLen = Param.SplicingLength
-	}
+	CorbaCMD.AddCommand(prepareCMD(), validateCMD(), annoGATKSNVCMD(), annoXHMMCNVCMD())
 }
 
 func main() {
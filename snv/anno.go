@@ -3,22 +3,22 @@ package snv
 import (
 	"bytes"
 	"grandanno/data"
-	"log"
-	"os"
 	"strconv"
 	"strings"
 )
 
 // Annotation 注释结果
 type Annotation struct {
-	Gene       string `json:"gene"`
-	EntrezID   int    `json:"entrez_id"`
-	Transcript string `json:"transcript"`
-	Exon       string `json:"exon"`
-	NaChange   string `json:"na_change"`
-	AaChange   string `json:"aa_change"`
-	Region     string `json:"region"`
-	Function   string `json:"function"`
+	Gene         string   `json:"gene"`
+	EntrezID     int      `json:"entrez_id"`
+	Transcript   string   `json:"transcript"`
+	Exon         string   `json:"exon"`
+	NaChange     string   `json:"na_change"`
+	AaChange     string   `json:"aa_change"`
+	Region       string   `json:"region"`
+	Function     string   `json:"function"`
+	Consequences []string `json:"consequences"`
+	Impact       string   `json:"impact"`
 }
 
 // SetExon 设置外显子信息
@@ -43,22 +43,26 @@ func (annos Annotations) IsSpecial() bool {
 }
 
 // AnnoIntergeic 注释基因间区
-func (annos *Annotations) AnnoIntergeic() {
-	*annos = append(*annos, Annotation{Region: "intergenic"})
+func (annos *Annotations) AnnoIntergeic(snv Snv) {
+	anno := Annotation{Region: "intergenic"}
+	anno.SetConsequences(snv, data.Refgene{})
+	*annos = append(*annos, anno)
 }
 
 // AnnoStream 注释上下游区
 func (annos *Annotations) AnnoStream(snv Snv, refgenes data.Refgenes) {
 	for _, refgene := range refgenes {
 		for _, region := range refgene.Streams {
-			variant := snv.GetVariant()
+			variant := getStrandSnv(snv, refgene.Strand).GetVariant()
 			if variant.Start <= region.End && variant.End >= region.Start {
-				*annos = append(*annos, Annotation{
+				anno := Annotation{
 					Gene:       refgene.Gene,
 					EntrezID:   refgene.EntrezID,
 					Transcript: refgene.Transcript,
 					Region:     region.Typo,
-				})
+				}
+				anno.SetConsequences(snv, refgene)
+				*annos = append(*annos, anno)
 				break
 			}
 		}
@@ -70,6 +74,7 @@ func (annos *Annotations) AnnoStream(snv Snv, refgenes data.Refgenes) {
 func (annos *Annotations) AnnoGene(snv Snv, refgenes data.Refgenes, splicingLen int) {
 	var cmplAnnos, incmplAnnos, unkAnnos Annotations
 	for _, refgene := range refgenes {
+		snv := getStrandSnv(snv, refgene.Strand)
 		variant := snv.GetVariant()
 		if variant.End >= refgene.ExonStart && variant.Start <= refgene.ExonEnd {
 			anno := Annotation{
@@ -79,6 +84,7 @@ func (annos *Annotations) AnnoGene(snv Snv, refgenes data.Refgenes, splicingLen
 			}
 			if refgene.Tag == "unk" {
 				anno.Region = "unkCDS"
+				anno.SetConsequences(snv, refgene)
 				unkAnnos = append(unkAnnos, anno)
 			} else {
 				switch snv.GetType() {
@@ -86,15 +92,19 @@ func (annos *Annotations) AnnoGene(snv Snv, refgenes data.Refgenes, splicingLen
 					anno.AnnoDel(snv, refgene, splicingLen)
 				case "ins":
 					anno.AnnoIns(snv, refgene, splicingLen)
+				case "delins":
+					anno.AnnoDelins(snv, refgene, splicingLen)
 				case "snp":
 					anno.AnnoSnp(snv, refgene, splicingLen)
 				default:
 					anno.AnnoSnp(snv, refgene, splicingLen)
 				}
 				if refgene.IsCmpl() {
+					anno.SetConsequences(snv, refgene)
 					cmplAnnos = append(cmplAnnos, anno)
 				} else {
 					anno.Function = "incmplCDS"
+					anno.SetConsequences(snv, refgene)
 					incmplAnnos = append(incmplAnnos, anno)
 				}
 			}
@@ -109,41 +119,88 @@ func (annos *Annotations) AnnoGene(snv Snv, refgenes data.Refgenes, splicingLen
 	}
 }
 
-// RunAnnotation 运行注释
-func RunAnnotation(snvs Snvs, refgeneMap map[string]data.Refgene, refidxs data.Refidxs, outJSONFile string) {
-	log.Printf("start run annotation of snv\n")
-	fp, err := os.Create(outJSONFile)
-	if err != nil {
-		log.Fatal(err)
+// Result SNV及其注释结果，OffPanel为true时不在panel中且Annotations为空
+type Result struct {
+	Snv          Snv                       `json:"snv"`
+	Normalized   *data.Variant             `json:"normalized,omitempty"`
+	Annotations  Annotations               `json:"annotations"`
+	Phased       []PhasedAnnotation        `json:"phased,omitempty"`
+	Consequence  string                    `json:"most_severe_consequence,omitempty"`
+	Impact       string                    `json:"impact,omitempty"`
+	Frequencies  map[string]data.Frequency `json:"frequencies,omitempty"`
+	Clinvar      *data.Clinvar             `json:"clinvar,omitempty"`
+	ClinvarExons []data.ClinvarExon        `json:"clinvar_exons,omitempty"`
+	Dbsnp        string                    `json:"dbsnp,omitempty"`
+	OffPanel     bool                      `json:"off_panel,omitempty"`
+}
+
+// GetNormalizedVariant 获取用于数据库匹配的变异：存在左对齐后的变异时使用左对齐后的变异
+func (result Result) GetNormalizedVariant() data.Variant {
+	if result.Normalized != nil {
+		return *result.Normalized
 	}
-	defer fp.Close()
-	for i, j := 0, 0; i < len(snvs) && j < len(refidxs); {
-		snvPos1, snvPos2 := snvs[i].GetVariant().GetNumericalPosition()
-		refPos1, refPos2 := refidxs[j].GetNumericalPosition()
-		if snvPos1 > refPos2 {
-			j++
-		} else {
-			annos := make(Annotations, 0)
-			if snvPos2 < refPos1 {
-				annos.AnnoIntergeic()
-			} else {
-				refgenes := refidxs[j].GetRefgenes(refgeneMap)
-				annos.AnnoGene(snvs[i], refgenes, data.Config.Param.SplicingLen)
-				if len(annos) == 0 {
-					annos.AnnoStream(snvs[i], refgenes)
-				}
-				if len(annos) == 0 {
-					annos.AnnoIntergeic()
-				}
-			}
-			json, err := data.ConvertToJSON(map[string]interface{}{"snv": snvs[i], "annotations": annos})
-			if err != nil {
-				log.Fatal(err)
-			}
-			if _, err := fp.WriteString(json + "\n"); err != nil {
-				log.Fatal(err)
+	return result.Snv.GetVariant()
+}
+
+// GetPhasedAnnotation 获取转录本上同一密码子中同相位SNV合并后的注释
+func (result Result) GetPhasedAnnotation(transcript string) (PhasedAnnotation, bool) {
+	for _, phasedAnno := range result.Phased {
+		if phasedAnno.Transcript == transcript {
+			return phasedAnno, true
+		}
+	}
+	return PhasedAnnotation{}, false
+}
+
+// GetSampleResults 获取第i个样本的注释结果，结果中的SNV只保留该样本：
+// zygosities为空时获取样本基因型中含有ALT的VCF记录，否则获取样本合子状态为zygosities之一的变异
+func GetSampleResults(results []Result, i int, zygosities []string) (sampleResults []Result) {
+	for _, result := range results {
+		gatkSnv, ok := result.Snv.(GatkSnv)
+		if !ok || i >= len(gatkSnv.Samples) {
+			continue
+		}
+		if sample := gatkSnv.Samples[i]; len(zygosities) == 0 && !sample.IsVariant() ||
+			len(zygosities) > 0 && !sample.HasZygosity(zygosities) {
+			continue
+		}
+		result.Snv = gatkSnv.GetSample(i)
+		result.Phased = nil
+		sampleResults = append(sampleResults, result)
+	}
+	return
+}
+
+// FilterResultsByZygosity 获取任一样本合子状态为zygosities之一的注释结果，zygosities为空时不过滤
+func FilterResultsByZygosity(results []Result, zygosities []string) []Result {
+	if len(zygosities) == 0 {
+		return results
+	}
+	var filtered []Result
+	for _, result := range results {
+		gatkSnv, ok := result.Snv.(GatkSnv)
+		if !ok {
+			continue
+		}
+		for _, sample := range gatkSnv.Samples {
+			if sample.HasZygosity(zygosities) {
+				filtered = append(filtered, result)
+				break
 			}
-			i++
 		}
 	}
+	return filtered
+}
+
+// NewAnnotations 注释SNV：依次注释基因区、上下游区和基因间区；snv为ShiftedSnv时每个转录本使用其方向上3'端移位后的变异
+func NewAnnotations(snv Snv, refgenes data.Refgenes, splicingLen int) Annotations {
+	annos := make(Annotations, 0)
+	annos.AnnoGene(snv, refgenes, splicingLen)
+	if len(annos) == 0 {
+		annos.AnnoStream(snv, refgenes)
+	}
+	if len(annos) == 0 {
+		annos.AnnoIntergeic(snv)
+	}
+	return annos
 }
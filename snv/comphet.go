@@ -0,0 +1,206 @@
+package snv
+
+import (
+	"bufio"
+	"fmt"
+	"grandanno/data"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// 复合杂合候选中两个变异的相位关系
+const (
+	CompHetTrans   = "trans"
+	CompHetUnknown = "unknown"
+)
+
+// compHetVariant 样本在某个基因中的杂合变异
+type compHetVariant struct {
+	sn         string
+	record     string
+	allele     int
+	transcript string
+	naChange   string
+	aaChange   string
+	sample     GatkSample
+}
+
+// getParent 获取变异的来源亲本(father/mother)，来源不唯一或未知时返回空字符串
+func (variant compHetVariant) getParent() string {
+	father, mother := false, false
+	for _, tag := range variant.sample.Inheritance {
+		switch tag {
+		case InheritanceFather:
+			father = true
+		case InheritanceMother:
+			mother = true
+		}
+	}
+	if father && !mother {
+		return "father"
+	} else if mother && !father {
+		return "mother"
+	}
+	return ""
+}
+
+// CompHetCandidate 复合杂合候选：同一样本同一基因中的两个杂合变异，Evidence为判断位于不同单倍型的依据
+type CompHetCandidate struct {
+	Sample   string
+	Gene     string
+	EntrezID int
+	Variants [2]compHetVariant
+	Phase    string
+	Evidence string
+}
+
+// getCompHetPhase 判断两个杂合变异的相位关系：同一多等位基因位点的不同ALT、不同单倍型(PS/PGT/PID)或来自不同亲本时为trans，
+// 同一单倍型或来自同一亲本时为cis(不是候选)，否则为unknown
+func getCompHetPhase(variant1 compHetVariant, variant2 compHetVariant) (phase string, evidence string, ok bool) {
+	if variant1.record == variant2.record {
+		return CompHetTrans, "genotype", true
+	}
+	phase1, phase2 := variant1.sample.Phase, variant2.sample.Phase
+	if phase1 != nil && phase2 != nil && phase1.Set == phase2.Set {
+		haplotypes1, haplotypes2 := phase1.GetHaplotypes(variant1.allele), phase2.GetHaplotypes(variant2.allele)
+		for _, h1 := range haplotypes1 {
+			for _, h2 := range haplotypes2 {
+				if h1 == h2 {
+					return "", "", false
+				}
+			}
+		}
+		if len(haplotypes1) > 0 && len(haplotypes2) > 0 {
+			return CompHetTrans, "phase", true
+		}
+	}
+	parent1, parent2 := variant1.getParent(), variant2.getParent()
+	if parent1 != "" && parent2 != "" {
+		if parent1 == parent2 {
+			return "", "", false
+		}
+		return CompHetTrans, "pedigree", true
+	}
+	return CompHetUnknown, "", true
+}
+
+// CompHetCollector 收集各样本各基因中影响程度为HIGH/MODERATE的杂合变异，用于检出复合杂合候选
+type CompHetCollector struct {
+	groups map[string][]compHetVariant
+	keys   []string
+}
+
+// NewCompHetCollector 创建复合杂合候选收集器
+func NewCompHetCollector() *CompHetCollector {
+	return &CompHetCollector{groups: make(map[string][]compHetVariant)}
+}
+
+// Add 添加一批注释结果中各样本的杂合变异，每个基因只取第一个影响程度为HIGH/MODERATE的转录本注释
+func (collector *CompHetCollector) Add(results []Result) {
+	for _, result := range results {
+		gatkSnv, ok := result.Snv.(GatkSnv)
+		if !ok {
+			continue
+		}
+		genes := make(map[string]Annotation)
+		var geneKeys []string
+		for _, anno := range result.Annotations {
+			key := fmt.Sprintf("%s\t%d", anno.Gene, anno.EntrezID)
+			if _, ok := genes[key]; ok || anno.Gene == "" || anno.Impact != data.ImpactHigh && anno.Impact != data.ImpactModerate {
+				continue
+			}
+			genes[key] = anno
+			geneKeys = append(geneKeys, key)
+		}
+		for _, sample := range gatkSnv.Samples {
+			if sample.Zygosity != ZygosityHet {
+				continue
+			}
+			for _, geneKey := range geneKeys {
+				anno := genes[geneKey]
+				key := sample.Name + "\t" + geneKey
+				if _, ok := collector.groups[key]; !ok {
+					collector.keys = append(collector.keys, key)
+				}
+				collector.groups[key] = append(collector.groups[key], compHetVariant{
+					sn:         gatkSnv.Variant.GetSn(),
+					record:     gatkSnv.OtherInfo,
+					allele:     gatkSnv.AltIndex + 1,
+					transcript: anno.Transcript,
+					naChange:   anno.NaChange,
+					aaChange:   anno.AaChange,
+					sample:     sample,
+				})
+			}
+		}
+	}
+}
+
+// GetCandidates 获取复合杂合候选：同一样本同一基因中除确定位于同一单倍型(cis)外的所有杂合变异对
+func (collector *CompHetCollector) GetCandidates() (candidates []CompHetCandidate) {
+	for _, key := range collector.keys {
+		variants := collector.groups[key]
+		field := strings.Split(key, "\t")
+		entrezID, _ := strconv.Atoi(field[2])
+		for i := 0; i < len(variants); i++ {
+			for j := i + 1; j < len(variants); j++ {
+				phase, evidence, ok := getCompHetPhase(variants[i], variants[j])
+				if !ok {
+					continue
+				}
+				candidates = append(candidates, CompHetCandidate{
+					Sample:   field[0],
+					Gene:     field[1],
+					EntrezID: entrezID,
+					Variants: [2]compHetVariant{variants[i], variants[j]},
+					Phase:    phase,
+					Evidence: evidence,
+				})
+			}
+		}
+	}
+	return
+}
+
+// WriteCompHetReport 输出复合杂合候选报告(TSV)，每个候选变异对输出一行
+func WriteCompHetReport(outFile string, candidates []CompHetCandidate) error {
+	fp, err := os.Create(outFile)
+	if err != nil {
+		return err
+	}
+	writer := bufio.NewWriter(fp)
+	columns := []string{
+		"sample", "gene", "entrez_id", "variant1", "transcript1", "na_change1", "aa_change1",
+		"variant2", "transcript2", "na_change2", "aa_change2", "phase", "evidence",
+	}
+	if _, err := writer.WriteString("#" + strings.Join(columns, "\t") + "\n"); err != nil {
+		fp.Close()
+		return err
+	}
+	for _, candidate := range candidates {
+		var entrezID string
+		if candidate.EntrezID > 0 {
+			entrezID = strconv.Itoa(candidate.EntrezID)
+		}
+		values := []string{candidate.Sample, candidate.Gene, entrezID}
+		for _, variant := range candidate.Variants {
+			values = append(values, variant.sn, variant.transcript, variant.naChange, variant.aaChange)
+		}
+		values = append(values, candidate.Phase, candidate.Evidence)
+		for i, value := range values {
+			if value == "" {
+				values[i] = "."
+			}
+		}
+		if _, err := writer.WriteString(strings.Join(values, "\t") + "\n"); err != nil {
+			fp.Close()
+			return err
+		}
+	}
+	if err := writer.Flush(); err != nil {
+		fp.Close()
+		return err
+	}
+	return fp.Close()
+}
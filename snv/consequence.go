@@ -0,0 +1,137 @@
+package snv
+
+import (
+	"grandanno/data"
+	"strings"
+)
+
+// getSpliceSiteConsequence 根据变异两端在内含子中的偏移判断影响的是剪接供体(内含子5'端)还是剪接受体(内含子3'端)
+func getSpliceSiteConsequence(variant data.Variant, refgene data.Refgene) string {
+	for _, pos := range []int{variant.Start, variant.End} {
+		if _, offset, ok := getCdnaPos(refgene, pos); ok && offset > 0 {
+			return "splice_donor_variant"
+		} else if ok && offset < 0 {
+			return "splice_acceptor_variant"
+		}
+	}
+	return "splice_region_variant"
+}
+
+// getIndelConsequence 根据插入/缺失长度获取编码区变异的consequence
+func getIndelConsequence(snv Snv) string {
+	variant := snv.GetVariant()
+	var length int
+	switch snv.GetType() {
+	case "ins":
+		length = variant.Alt.GetLen()
+	case "del":
+		length = -variant.Ref.GetLen()
+	case "delins":
+		length = variant.Alt.GetLen() - variant.Ref.GetLen()
+	}
+	switch {
+	case length%3 != 0:
+		return "frameshift_variant"
+	case length > 0:
+		return "inframe_insertion"
+	case length < 0:
+		return "inframe_deletion"
+	case snv.GetType() == "delins":
+		return "protein_altering_variant"
+	default:
+		return "coding_sequence_variant"
+	}
+}
+
+// getCodingConsequences 根据Function获取编码区变异的consequences，Function为空时根据变异长度判断
+func (anno Annotation) getCodingConsequences(snv Snv) (terms []string) {
+	function := anno.Function
+	switch {
+	case function == "synonymous_snv" || function == "delins_synonymous":
+		if strings.HasPrefix(anno.AaChange, "p.Ter") {
+			terms = append(terms, "stop_retained_variant")
+		} else {
+			terms = append(terms, "synonymous_variant")
+		}
+	case function == "nonsynonymous_snv":
+		if strings.HasPrefix(anno.AaChange, "p.Met1") && !strings.HasPrefix(anno.AaChange, "p.Met1_") {
+			terms = append(terms, "start_lost")
+		} else {
+			terms = append(terms, "missense_variant")
+		}
+	case function == "stopgain":
+		terms = append(terms, "stop_gained")
+	case function == "stoploss":
+		terms = append(terms, "stop_lost")
+	case function == "incmplCDS":
+		terms = append(terms, "coding_sequence_variant")
+	case strings.Contains(function, "_nonframeshift"):
+		if consequence := getIndelConsequence(snv); consequence != "frameshift_variant" {
+			terms = append(terms, consequence)
+		} else {
+			terms = append(terms, "protein_altering_variant")
+		}
+	case strings.Contains(function, "_frameshift"):
+		terms = append(terms, "frameshift_variant")
+	case snv.GetType() == "snp":
+		terms = append(terms, "coding_sequence_variant")
+	default:
+		terms = append(terms, getIndelConsequence(snv))
+	}
+	if strings.HasSuffix(function, "_stopgain") {
+		terms = append(terms, "stop_gained")
+	} else if strings.HasSuffix(function, "_stoploss") {
+		terms = append(terms, "stop_lost")
+	}
+	return
+}
+
+// SetConsequences 将Region/Function映射为Sequence Ontology consequence terms及影响程度，refgene为注释所用的转录本(上下游区及基因间区可为空)
+func (anno *Annotation) SetConsequences(snv Snv, refgene data.Refgene) {
+	variant := snv.GetVariant()
+	region := anno.Region
+	var terms []string
+	switch {
+	case region == "intergenic":
+		terms = append(terms, "intergenic_variant")
+	case region == "upstream":
+		terms = append(terms, "upstream_gene_variant")
+	case region == "downstream":
+		terms = append(terms, "downstream_gene_variant")
+	case region == "unkCDS":
+		terms = append(terms, "non_coding_transcript_variant")
+	case region == "intronic":
+		terms = append(terms, "intron_variant")
+	case strings.HasSuffix(region, "splicing_site"):
+		terms = append(terms, getSpliceSiteConsequence(variant, refgene), "intron_variant")
+	case strings.HasSuffix(region, "splicing_region"):
+		terms = append(terms, "splice_region_variant", "intron_variant")
+	case strings.HasPrefix(region, "utr5"):
+		terms = append(terms, "5_prime_UTR_variant")
+	case strings.HasPrefix(region, "utr3"):
+		terms = append(terms, "3_prime_UTR_variant")
+	case region == "exonic" || strings.HasSuffix(region, "CDS_splicing"):
+		terms = append(terms, anno.getCodingConsequences(snv)...)
+		if region == "oCDS_splicing" {
+			terms = append(terms, getSpliceSiteConsequence(variant, refgene))
+		} else if region == "CDS_splicing" {
+			terms = append(terms, "splice_region_variant")
+		}
+	}
+	if strings.HasSuffix(region, "exon_splicing") {
+		terms = append(terms, "splice_region_variant")
+	}
+	anno.Consequences = data.SortConsequences(terms)
+	if len(anno.Consequences) > 0 {
+		anno.Impact = data.GetConsequenceImpact(anno.Consequences[0])
+	}
+}
+
+// GetMostSevereConsequence 获取所有注释中最严重的consequence term及其影响程度
+func (annos Annotations) GetMostSevereConsequence() (string, string) {
+	var terms []string
+	for _, anno := range annos {
+		terms = append(terms, anno.Consequences...)
+	}
+	return data.GetMostSevereConsequence(terms)
+}
@@ -279,7 +279,7 @@ func (anno *Annotation) annoDelForward(variant data.Variant, refgene data.Refgen
 		}
 	}
 	if refgene.Tag == "cmpl" && (anno.Region == "exonic" || strings.HasSuffix(anno.Region, "CDS_splicing")) {
-		anno.annoCdsChangeOfDel(lenL, lenR, cdna, protein, refgene.Chrom == "MT")
+		anno.annoCdsChangeOfDel(lenL, lenR, cdna, protein, data.IsMitochondrion(refgene.Chrom))
 	}
 }
 
@@ -434,7 +434,7 @@ func (anno *Annotation) annoDelBackward(variant data.Variant, refgene data.Refge
 		}
 	}
 	if refgene.Tag == "cmpl" && (anno.Region == "exonic" || strings.HasSuffix(anno.Region, "CDS_splicing")) {
-		anno.annoCdsChangeOfDel(lenL, lenR, cdna, protein, refgene.Chrom == "MT")
+		anno.annoCdsChangeOfDel(lenL, lenR, cdna, protein, data.IsMitochondrion(refgene.Chrom))
 	}
 }
 
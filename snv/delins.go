@@ -0,0 +1,268 @@
+package snv
+
+import (
+	"fmt"
+	"grandanno/data"
+	"strings"
+)
+
+// getRegionIndex 获取转录本方向上第k个区域元件的下标
+func getRegionIndex(k int, count int, strand byte) int {
+	if strand == '-' {
+		return count - 1 - k
+	}
+	return k
+}
+
+// getCdnaPos 获取基因组位置在转录本上的位置：pos为相对CDS起始的位置(从1开始，5'UTR中<=0，3'UTR中大于CDS长度)，
+// offset为内含子中相对最近外显子的偏移(上一个外显子为正，下一个外显子为负)，位置不在转录本内时返回false
+func getCdnaPos(refgene data.Refgene, genomePos int) (pos int, offset int, ok bool) {
+	count := len(refgene.Regions)
+	exonLen, cdsStart := 0, 0
+	for k := 0; k < count; k++ {
+		region := refgene.Regions[getRegionIndex(k, count, refgene.Strand)]
+		if region.Typo == "cds" && cdsStart == 0 {
+			cdsStart = exonLen + 1
+		}
+		if region.Typo != "intron" {
+			exonLen += region.End - region.Start + 1
+		}
+	}
+	exonLen = 0
+	for k := 0; k < count; k++ {
+		region := refgene.Regions[getRegionIndex(k, count, refgene.Strand)]
+		if genomePos >= region.Start && genomePos <= region.End {
+			distance1, distance2 := genomePos-region.Start+1, region.End-genomePos+1
+			if refgene.Strand == '-' {
+				distance1, distance2 = distance2, distance1
+			}
+			if region.Typo != "intron" {
+				return exonLen + distance1 - cdsStart + 1, 0, true
+			}
+			if distance1 <= distance2 {
+				return exonLen - cdsStart + 1, distance1, true
+			}
+			return exonLen - cdsStart + 2, -distance2, true
+		}
+		if region.Typo != "intron" {
+			exonLen += region.End - region.Start + 1
+		}
+	}
+	return 0, 0, false
+}
+
+// formatCdnaPos 格式化HGVS c.位置，cdsLen为CDS长度
+func formatCdnaPos(pos int, offset int, cdsLen int) string {
+	var value string
+	if pos <= 0 {
+		value = fmt.Sprintf("-%d", 1-pos)
+	} else if pos > cdsLen {
+		value = fmt.Sprintf("*%d", pos-cdsLen)
+	} else {
+		value = fmt.Sprintf("%d", pos)
+	}
+	if offset > 0 {
+		value += fmt.Sprintf("+%d", offset)
+	} else if offset < 0 {
+		value += fmt.Sprintf("%d", offset)
+	}
+	return value
+}
+
+// getAaRangeChange 获取氨基酸区间protein[start:end]被替换为alt的HGVS
+func getAaRangeChange(protein data.Sequence, start int, end int, alt data.Sequence) string {
+	aaStart := fmt.Sprintf("%s%d", data.GetOne2Three(protein.GetChar(start)), start+1)
+	if end-start == 1 && alt.GetLen() == 1 {
+		return fmt.Sprintf("p.%s%s", aaStart, data.GetOne2Three(alt.GetChar(0)))
+	}
+	if end-start > 1 {
+		aaStart = fmt.Sprintf("%s_%s%d", aaStart, data.GetOne2Three(protein.GetChar(end-1)), end)
+	}
+	if alt.IsEmpty() {
+		return fmt.Sprintf("p.%sdel", aaStart)
+	}
+	return fmt.Sprintf("p.%sdelins%s", aaStart, alt.GetOne2Tree())
+}
+
+func (anno *Annotation) annoCdsChangeOfDelins(start int, end int, alt data.Sequence, cdna data.Sequence, protein data.Sequence, isMt bool) {
+	varCdna := cdna.GetSeq(0, start-1) + alt + cdna.GetSeq(end, -1)
+	varProtein := varCdna.Translate(isMt)
+	if start == end {
+		anno.NaChange = fmt.Sprintf("c.%ddelins%s", start, alt)
+	} else {
+		anno.NaChange = fmt.Sprintf("c.%d_%ddelins%s", start, end, alt)
+	}
+	lenp, lenvp := protein.GetLen(), varProtein.GetLen()
+	lenl, lenr := 0, 0
+	for lenl < lenp && lenl < lenvp && protein.GetChar(lenl) == varProtein.GetChar(lenl) {
+		lenl++
+	}
+	if lenl == lenp && lenl == lenvp {
+		aa := data.GetOne2Three(protein.GetChar((start - 1) / 3))
+		anno.Function = "delins_synonymous"
+		anno.AaChange = fmt.Sprintf("p.%s%d%s", aa, (start-1)/3+1, aa)
+		return
+	}
+	if lenl >= lenp {
+		// 变异后的蛋白包含完整的原蛋白(包括终止密码子)，只有终止密码子之后的序列发生变化
+		aa := data.GetOne2Three(protein.GetChar(lenp - 1))
+		anno.Function = "delins_synonymous"
+		anno.AaChange = fmt.Sprintf("p.%s%d%s", aa, lenp, aa)
+		return
+	}
+	var function string
+	if (alt.GetLen()-(end-start+1))%3 != 0 {
+		function = "delins_frameshift"
+		anno.AaChange = fmt.Sprintf(
+			"p.%s%d%sfs",
+			data.GetOne2Three(protein.GetChar(lenl)),
+			lenl+1,
+			data.GetOne2Three(varProtein.GetChar(lenl)),
+		)
+	} else {
+		function = "delins_nonframeshift"
+		for lenr < lenp-lenl && lenr < lenvp-lenl && protein.GetChar(lenp-lenr-1) == varProtein.GetChar(lenvp-lenr-1) {
+			lenr++
+		}
+		altAa := varProtein.GetSeq(lenl, lenvp-lenr-lenl)
+		if lenp-lenr == lenl {
+			if lenl > 0 {
+				anno.AaChange = fmt.Sprintf(
+					"p.%s%d_%s%dins%s",
+					data.GetOne2Three(protein.GetChar(lenl-1)),
+					lenl,
+					data.GetOne2Three(protein.GetChar(lenl)),
+					lenl+1,
+					altAa.GetOne2Tree(),
+				)
+			} else {
+				// 插入位于第一个氨基酸之前，以第一个氨基酸的替换表示
+				anno.AaChange = getAaRangeChange(protein, 0, 1, altAa+protein.GetSeq(0, 1))
+			}
+		} else {
+			anno.AaChange = getAaRangeChange(protein, lenl, lenp-lenr, altAa)
+		}
+	}
+	if stopIndex := varProtein.GetIndex('*'); stopIndex < 0 {
+		anno.Function = function + "_stoploss"
+	} else if stopIndex < lenvp-1 {
+		anno.Function = function + "_stopgain"
+	} else {
+		anno.Function = function
+	}
+}
+
+// annoRegionOfDelins 注释完全位于一个区域元件中的delins所在区域，prev/next为转录本方向上的前后区域元件
+func (anno *Annotation) annoRegionOfDelins(variant data.Variant, refgene data.Refgene, index int, splicingLen int) {
+	region := refgene.Regions[index]
+	prevRegion, hasPrev := refgene.Regions.GetPrev(index, refgene.Strand)
+	nextRegion, hasNext := refgene.Regions.GetNext(index, refgene.Strand)
+	distance1, distance2 := variant.Start-region.Start+1, region.End-variant.End+1
+	if refgene.Strand == '-' {
+		distance1, distance2 = distance2, distance1
+	}
+	switch {
+	case region.Typo == "intron":
+		var neighbor data.Region
+		if distance1 <= splicingLen && hasPrev {
+			neighbor = prevRegion
+		} else if distance2 <= splicingLen && hasNext {
+			neighbor = nextRegion
+			distance1 = distance2
+		} else {
+			anno.Region = "intronic"
+			return
+		}
+		if distance1 <= 2 {
+			anno.Region = "splicing_site"
+		} else {
+			anno.Region = "splicing_region"
+		}
+		if neighbor.Typo == "cds" {
+			anno.SetExon(neighbor.ExonOrder)
+		} else {
+			anno.Region = strings.Join([]string{neighbor.Typo, anno.Region}, "_")
+		}
+	case strings.HasPrefix(region.Typo, "utr"):
+		if distance1 <= splicingLen && hasPrev && prevRegion.Typo == "intron" ||
+			distance2 <= splicingLen && hasNext && nextRegion.Typo == "intron" {
+			anno.Region = strings.Join([]string{region.Typo, "exon_splicing"}, "_")
+		} else {
+			anno.Region = region.Typo
+		}
+	default:
+		anno.SetExon(region.ExonOrder)
+		if distance1 <= splicingLen && hasPrev && prevRegion.Typo == "intron" ||
+			distance2 <= splicingLen && hasNext && nextRegion.Typo == "intron" {
+			anno.Region = "CDS_splicing"
+		} else {
+			anno.Region = "exonic"
+		}
+	}
+}
+
+// AnnoDelins 注释多碱基替换(delins/MNV)：跨越外显子/内含子边界时为oCDS_splicing或UTR的exon_splicing，跨越UTR/CDS边界时为exonic
+func (anno *Annotation) AnnoDelins(delins Snv, refgene data.Refgene, splicingLen int) {
+	variant := delins.GetVariant()
+	var indexes []int
+	hasCds, hasIntron, utrTypo, exonOrder := false, false, "", 0
+	for k := 0; k < len(refgene.Regions); k++ {
+		index := getRegionIndex(k, len(refgene.Regions), refgene.Strand)
+		region := refgene.Regions[index]
+		if region.Start > variant.End || region.End < variant.Start {
+			continue
+		}
+		indexes = append(indexes, index)
+		switch {
+		case region.Typo == "cds":
+			if !hasCds {
+				exonOrder = region.ExonOrder
+			}
+			hasCds = true
+		case region.Typo == "intron":
+			hasIntron = true
+		case utrTypo == "":
+			utrTypo = region.Typo
+		}
+	}
+	switch {
+	case len(indexes) == 0:
+		return
+	case len(indexes) == 1:
+		anno.annoRegionOfDelins(variant, refgene, indexes[0], splicingLen)
+	case hasCds && hasIntron:
+		anno.Region = "oCDS_splicing"
+		anno.SetExon(exonOrder)
+	case hasCds:
+		anno.Region = "exonic"
+		anno.SetExon(exonOrder)
+	case utrTypo != "":
+		anno.Region = utrTypo + "_exon_splicing"
+	default:
+		anno.Region = "intronic"
+	}
+	if refgene.Tag != "cmpl" || !hasCds && !strings.HasPrefix(anno.Region, "splicing") {
+		return
+	}
+	start, offset1, ok1 := getCdnaPos(refgene, variant.Start)
+	end, offset2, ok2 := getCdnaPos(refgene, variant.End)
+	if !ok1 || !ok2 {
+		return
+	}
+	alt := variant.Alt
+	if refgene.Strand == '-' {
+		start, offset1, end, offset2 = end, offset2, start, offset1
+		alt.ReverseComplement()
+	}
+	cdsLen := refgene.Cdna.GetLen()
+	if len(indexes) == 1 && offset1 == 0 && offset2 == 0 && start >= 1 && end <= cdsLen {
+		anno.annoCdsChangeOfDelins(start, end, alt, refgene.Cdna, refgene.Protein, data.IsMitochondrion(refgene.Chrom))
+		return
+	}
+	pos1, pos2 := formatCdnaPos(start, offset1, cdsLen), formatCdnaPos(end, offset2, cdsLen)
+	if pos1 == pos2 {
+		anno.NaChange = fmt.Sprintf("c.%sdelins%s", pos1, alt)
+	} else {
+		anno.NaChange = fmt.Sprintf("c.%s_%sdelins%s", pos1, pos2, alt)
+	}
+}
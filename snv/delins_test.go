@@ -0,0 +1,25 @@
+package snv
+
+import "testing"
+
+func TestAnnoDelinsMinusStrand(t *testing.T) {
+	refgene := newTestRefgene('-')
+	if refgene.Protein != "MAKPEW*" {
+		t.Fatalf("protein = %s, want MAKPEW*", refgene.Protein)
+	}
+	// c.5_6CT>TG：GCT(Ala)->GTG(Val)，基因组正链上为130-131 AG>CA
+	var anno Annotation
+	anno.AnnoDelins(newTestSnv(130, 131, "AG", "CA"), refgene, 2)
+	if anno.NaChange != "c.5_6delinsTG" || anno.AaChange != "p.Ala2Val" || anno.Function != "delins_nonframeshift" {
+		t.Errorf("got %s %s %s, want c.5_6delinsTG p.Ala2Val delins_nonframeshift", anno.NaChange, anno.AaChange, anno.Function)
+	}
+}
+
+func TestAnnoDelinsBeforeFirstCodon(t *testing.T) {
+	// c.1_3delinsGGGATG：ATG前插入GGG，蛋白为GMAKPEW*
+	var anno Annotation
+	anno.AnnoDelins(newTestSnv(103, 105, "ATG", "GGGATG"), newTestRefgene('+'), 2)
+	if anno.AaChange != "p.Met1delinsGlyMet" || anno.Function != "delins_nonframeshift" {
+		t.Errorf("got %s %s, want p.Met1delinsGlyMet delins_nonframeshift", anno.AaChange, anno.Function)
+	}
+}
@@ -1,26 +1,25 @@
 package snv
 
 import (
-	"bytes"
+	"fmt"
 	"grandanno/data"
-	"log"
-	"os"
-	"sort"
 	"strconv"
 	"strings"
 )
 
-// GatkSnv GATK4 SNV 信息
+// GatkSnv GATK4 SNV 信息，Information.AlleleFreq为INFO中的AF，JSON中沿用原来的genotype字段名
 type GatkSnv struct {
 	Variant     data.Variant `json:"variant"`
 	Information struct {
 		Depth      int     `json:"depth"`
 		Qual       float64 `json:"qual"`
 		GatkFilter string  `json:"gatk_fitler"`
-		Genotype   float64 `json:"genotype"`
+		AlleleFreq float64 `json:"genotype"`
 		Ratio      float64 `json:"ratio"`
 	} `json:"information"`
-	OtherInfo string `json:"other_info"`
+	Samples   []GatkSample `json:"samples,omitempty"`
+	OtherInfo string       `json:"other_info"`
+	AltIndex  int          `json:"-"`
 }
 
 // GetVariant 获取变异信息
@@ -28,7 +27,7 @@ func (snv GatkSnv) GetVariant() data.Variant {
 	return snv.Variant
 }
 
-// GetType 获取变异类型
+// GetType 获取变异类型(snp/ins/del/delins)
 func (snv GatkSnv) GetType() string {
 	var typo string
 	switch {
@@ -36,22 +35,27 @@ func (snv GatkSnv) GetType() string {
 		typo = "ins"
 	case snv.Variant.Alt.GetChar(0) == '-':
 		typo = "del"
+	case snv.Variant.Ref.GetLen() > 1 || snv.Variant.Alt.GetLen() > 1:
+		typo = "delins"
 	default:
 		typo = "snp"
 	}
 	return typo
 }
 
-// InitGatkSnv 初始化GATK SNV
-func InitGatkSnv(vcfLine string) (gatkSnvs Snvs, err error) {
+// InitGatkSnv 初始化GATK SNV，samples为#CHROM行中的样本名，sexes为样本性别(用于判断性染色体上的倍性)，
+// 每个样本的FORMAT信息保存在Samples中，Information中的深度和变异比率取第一个样本
+func InitGatkSnv(samples []string, sexes map[string]string, vcfLine string) (gatkSnvs Snvs, err error) {
 	field := strings.Split(vcfLine, "\t")
-	chrom := strings.Replace(field[0], "chr", "", 1)
+	if len(field) < 8 {
+		err = fmt.Errorf("invalid vcf line: %s", vcfLine)
+		return
+	}
+	chrom := field[0]
 	ref := field[3]
 	alts := strings.Split(field[4], ",")
 	gatkFilter := field[6]
 	infoFeilds := strings.Split(field[7], ";")
-	formatKeys := strings.Split(field[len(field)-2], ":")
-	formatValues := strings.Split(field[len(field)-1], ":")
 	qual, err := strconv.ParseFloat(field[5], 32)
 	if err != nil {
 		qual = -1
@@ -61,50 +65,29 @@ func InitGatkSnv(vcfLine string) (gatkSnvs Snvs, err error) {
 		return
 	}
 	varCount := len(alts)
-	// 获取基因型，覆盖深度、变异比率
-	genotypes := make([]float64, varCount)
-	ratios := make([]float64, varCount)
-	depth := -1
+	// 获取INFO中的等位基因频率
+	freqs := make([]float64, varCount)
 	for i := 0; i < varCount; i++ {
-		genotypes[i] = float64(-1)
-		ratios[i] = float64(-1)
+		freqs[i] = float64(-1)
 	}
 	for _, info := range infoFeilds {
 		if strings.HasPrefix(info, "AF=") {
-			for i, gt := range strings.Split(info[3:], ",") {
-				if i > varCount {
+			for i, af := range strings.Split(info[3:], ",") {
+				if i >= varCount {
 					break
 				}
-				if _gt, err := strconv.ParseFloat(gt, 32); err == nil {
-					genotypes[i] = float64(_gt)
+				if freq, err := strconv.ParseFloat(af, 64); err == nil {
+					freqs[i] = freq
 				}
 			}
 			break
 		}
 	}
-	for i, key := range formatKeys {
-		if key == "DP" {
-			if dp, err := strconv.Atoi(formatValues[i]); err == nil {
-				depth = dp
-			}
-		}
-		if key == "AD" {
-			sum := 0
-			varCounts := make([]int, varCount)
-			counts := strings.Split(formatValues[i], ",")
-			for i := 0; i <= varCount && i < len(counts); i++ {
-				if c, err := strconv.Atoi(counts[i]); err == nil {
-					sum += c
-					if i > 0 {
-						varCounts[i-1] = c
-					}
-				}
-			}
-			if sum > 0 {
-				for i := 0; i < varCount; i++ {
-					ratios[i] = float64(varCounts[i]) / float64(sum)
-				}
-			}
+	var formats []gatkFormat
+	if len(field) > 9 {
+		formatKeys := strings.Split(field[8], ":")
+		for _, value := range field[9:] {
+			formats = append(formats, newGatkFormat(formatKeys, value, varCount))
 		}
 	}
 	for i, alt := range alts {
@@ -120,43 +103,33 @@ func InitGatkSnv(vcfLine string) (gatkSnvs Snvs, err error) {
 				Alt:   data.Sequence(alt),
 			},
 			OtherInfo: vcfLine,
+			AltIndex:  i,
 		}
-		gatkSnv.Information.Depth = depth
+		gatkSnv.Information.Depth = -1
 		gatkSnv.Information.Qual = qual
 		gatkSnv.Information.GatkFilter = gatkFilter
-		gatkSnv.Information.Genotype = genotypes[i]
-		gatkSnv.Information.Ratio = ratios[i]
+		gatkSnv.Information.AlleleFreq = freqs[i]
+		gatkSnv.Information.Ratio = -1
+		for j, format := range formats {
+			name := getSampleName(samples, j)
+			sex := getSampleSex(sexes, name)
+			gatkSnv.Samples = append(gatkSnv.Samples, format.getSample(name, sex, i, data.GetPloidy(chrom, pos, sex)))
+		}
+		if len(gatkSnv.Samples) > 0 {
+			gatkSnv.Information.Depth = gatkSnv.Samples[0].Depth
+			gatkSnv.Information.Ratio = gatkSnv.Samples[0].Ratio
+		}
 		gatkSnv.Variant.ConvertSnv()
 		gatkSnvs = append(gatkSnvs, gatkSnv)
 	}
 	return
 }
 
-// ReadGatkVcfFile 读取GATK VCF文件
-func ReadGatkVcfFile(vcfFile string, gatkSnvsChan chan Snvs) {
-	log.Printf("start read %s\n", vcfFile)
-	gatkSnvs := make(Snvs, 0)
-	fp, err := os.Open(vcfFile)
-	if err != nil {
-		log.Fatal(err)
-	}
-	defer fp.Close()
-	// var lines [][]byte
-	lines, err := data.ReadFile(vcfFile)
-	if err != nil {
-		log.Fatal(err)
-	}
-	for _, line := range lines {
-		line = bytes.TrimSpace(line)
-		if len(line) == 0 || line[0] == '#' {
-			continue
-		}
-		gatkSnv, err := InitGatkSnv(string(line))
-		if err != nil {
-			log.Fatal(err)
-		}
-		gatkSnvs = append(gatkSnvs, gatkSnv...)
-	}
-	sort.Sort(gatkSnvs)
-	gatkSnvsChan <- gatkSnvs
+// GetSample 获取只包含第i个样本的SNV，深度和变异比率取该样本
+func (snv GatkSnv) GetSample(i int) GatkSnv {
+	sample := snv.Samples[i]
+	snv.Samples = []GatkSample{sample}
+	snv.Information.Depth = sample.Depth
+	snv.Information.Ratio = sample.Ratio
+	return snv
 }
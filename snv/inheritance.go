@@ -0,0 +1,100 @@
+package snv
+
+import (
+	"grandanno/data"
+)
+
+// InheritanceMinDepth 判断新发变异时父母基因型所需的最小覆盖深度
+const InheritanceMinDepth = 10
+
+// 遗传方式标签
+const (
+	InheritanceDeNovo  = "de_novo"
+	InheritanceArHom   = "ar_homozygous"
+	InheritanceXLinked = "x_linked"
+	InheritanceFather  = "inherited_father"
+	InheritanceMother  = "inherited_mother"
+)
+
+// isCarrier 样本是否携带该ALT
+func (sample GatkSample) isCarrier() bool {
+	return sample.HasZygosity([]string{ZygosityHet, ZygosityHomAlt, ZygosityHemizygous})
+}
+
+// isReliableRef 样本是否以足够的深度检出为不携带该ALT
+func (sample GatkSample) isReliableRef() bool {
+	return sample.Zygosity == ZygosityHomRef && sample.Depth >= InheritanceMinDepth
+}
+
+// getInheritance 根据父母基因型获取子代变异的遗传方式，father/mother不在VCF中时为nil；
+// 男性X/Y非PAR区只考虑传递该染色体的亲本(X来自母亲，Y来自父亲)
+func getInheritance(child GatkSample, father *GatkSample, mother *GatkSample, chrom string, pos int) (tags []string) {
+	if !child.isCarrier() || father == nil && mother == nil {
+		return
+	}
+	sexChrom := data.GetSexChrom(chrom)
+	if child.Ploidy == 1 && sexChrom == "X" {
+		father = nil
+	} else if child.Ploidy == 1 && sexChrom == "Y" {
+		mother = nil
+	}
+	var parents []*GatkSample
+	for _, parent := range []*GatkSample{father, mother} {
+		if parent != nil {
+			parents = append(parents, parent)
+		}
+	}
+	isDeNovo := child.Ploidy == 1 && len(parents) == 1 || len(parents) == 2
+	for _, parent := range parents {
+		isDeNovo = isDeNovo && parent.isReliableRef()
+	}
+	if isDeNovo {
+		tags = append(tags, InheritanceDeNovo)
+	}
+	if sexChrom == "" && !data.IsMitochondrion(chrom) && child.Zygosity == ZygosityHomAlt &&
+		father != nil && mother != nil && father.Zygosity == ZygosityHet && mother.Zygosity == ZygosityHet {
+		tags = append(tags, InheritanceArHom)
+	}
+	if sexChrom == "X" && !data.IsPar(chrom, pos) && mother != nil && mother.isCarrier() &&
+		(child.Zygosity == ZygosityHemizygous || child.Zygosity == ZygosityHomAlt && father != nil && father.isCarrier()) {
+		tags = append(tags, InheritanceXLinked)
+	}
+	if father != nil && father.isCarrier() {
+		tags = append(tags, InheritanceFather)
+	}
+	if mother != nil && mother.isCarrier() {
+		tags = append(tags, InheritanceMother)
+	}
+	return
+}
+
+// SetInheritance 根据家系为VCF中父母也在VCF中的样本标记每个变异的遗传方式
+func SetInheritance(snvs Snvs, pedigree data.Pedigree) {
+	if len(pedigree) == 0 {
+		return
+	}
+	for i, snv := range snvs {
+		gatkSnv, ok := snv.(GatkSnv)
+		if !ok {
+			continue
+		}
+		indexes := make(map[string]int)
+		for j, sample := range gatkSnv.Samples {
+			indexes[sample.Name] = j
+		}
+		getSample := func(id string) *GatkSample {
+			if j, ok := indexes[id]; ok && id != "" {
+				return &gatkSnv.Samples[j]
+			}
+			return nil
+		}
+		for j, sample := range gatkSnv.Samples {
+			if pedSample, ok := pedigree[sample.Name]; ok {
+				gatkSnv.Samples[j].Inheritance = getInheritance(
+					sample, getSample(pedSample.Father), getSample(pedSample.Mother), gatkSnv.Variant.Chrom, gatkSnv.Variant.Start,
+				)
+			}
+		}
+		snvs[i] = gatkSnv
+	}
+}
@@ -7,16 +7,20 @@ import (
 )
 
 func (anno *Annotation) annoCdsChangeOfIns(pos int, alt data.Sequence, cdna data.Sequence, protein data.Sequence, isMt bool) {
-	var varCdna, varProtein data.Sequence
-	varCdna = cdna.GetInsSequence(pos, alt)
-	if isMt {
-		varProtein = cdna.Translate(true)
-	} else {
-		varProtein = cdna.Translate(false)
-	}
+	varCdna := cdna.GetInsSequence(pos, alt)
+	varProtein := varCdna.Translate(isMt)
 	for i := 0; i < cdna.GetLen(); i++ {
 		if cdna.GetChar(i) != varCdna.GetChar(i) {
-			anno.NaChange = fmt.Sprintf("c.%dins%s", i, varCdna.GetSeq(i, len(alt)))
+			insSeq := varCdna.GetSeq(i, len(alt))
+			if i >= len(alt) && cdna.GetSeq(i-len(alt), len(alt)) == insSeq {
+				if len(alt) == 1 {
+					anno.NaChange = fmt.Sprintf("c.%ddup", i)
+				} else {
+					anno.NaChange = fmt.Sprintf("c.%d_%ddup", i-len(alt)+1, i)
+				}
+			} else {
+				anno.NaChange = fmt.Sprintf("c.%dins%s", i, insSeq)
+			}
 			break
 		}
 	}
@@ -31,8 +35,9 @@ func (anno *Annotation) annoCdsChangeOfIns(pos int, alt data.Sequence, cdna data
 		anno.Region = "utr3"
 	} else {
 		if alt.GetLen()%3 == 0 {
-			for i := protein.GetLen() - 1; i >= 0; i-- {
-				if protein.GetChar(i) != varProtein.GetChar(i) {
+			diff := varProtein.GetLen() - protein.GetLen()
+			for i := protein.GetLen() - 1; i >= 0 && i+diff >= 0; i-- {
+				if protein.GetChar(i) != varProtein.GetChar(i+diff) {
 					break
 				}
 				lenR++
@@ -51,7 +56,9 @@ func (anno *Annotation) annoCdsChangeOfIns(pos int, alt data.Sequence, cdna data
 					anno.Function = "ins_nonframeshift"
 				}
 			}
-			if start == end1 {
+			if start == end1 && start >= altAa.GetLen() && protein.GetSeq(start-altAa.GetLen(), altAa.GetLen()) == altAa {
+				anno.AaChange = getAaDupChange(protein, start-altAa.GetLen(), start)
+			} else if start == end1 {
 				anno.AaChange = fmt.Sprintf(
 					"p.%s%d_%s%dins%s",
 					data.GetOne2Three(protein.GetChar(start-1)),
@@ -83,7 +90,48 @@ func (anno *Annotation) annoCdsChangeOfIns(pos int, alt data.Sequence, cdna data
 	}
 }
 
-func (anno *Annotation) annoInsForward(variant data.Variant, refgene data.Refgene, splicingLen int) {
+// getAaDupChange 获取氨基酸重复的HGVS，重复区间为protein[start:end]
+func getAaDupChange(protein data.Sequence, start int, end int) string {
+	if end-start == 1 {
+		return fmt.Sprintf("p.%s%ddup", data.GetOne2Three(protein.GetChar(start)), start+1)
+	}
+	return fmt.Sprintf(
+		"p.%s%d_%s%ddup",
+		data.GetOne2Three(protein.GetChar(start)),
+		start+1,
+		data.GetOne2Three(protein.GetChar(end-1)),
+		end,
+	)
+}
+
+// getIntronDupChange 获取内含子中重复插入的HGVS：pos为转录本方向上内含子之前的CDS长度，
+// prev为是否相对上一个外显子定位；重复区间超出内含子时返回false
+func getIntronDupChange(variant data.Variant, region data.Region, strand byte, pos int, prev bool) (string, bool) {
+	length := variant.Alt.GetLen()
+	first, last := variant.Start-length+1, variant.Start
+	if strand == '-' {
+		first, last = variant.Start+length, variant.Start+1
+	}
+	if first < region.Start || first > region.End || last < region.Start || last > region.End {
+		return "", false
+	}
+	getPos := func(genomePos int) string {
+		offset1, offset2 := genomePos-region.Start+1, region.End-genomePos+1
+		if strand == '-' {
+			offset1, offset2 = offset2, offset1
+		}
+		if prev {
+			return fmt.Sprintf("%d+%d", pos, offset1)
+		}
+		return fmt.Sprintf("%d-%d", pos+1, offset2)
+	}
+	if length == 1 {
+		return fmt.Sprintf("c.%sdup", getPos(first)), true
+	}
+	return fmt.Sprintf("c.%s_%sdup", getPos(first), getPos(last)), true
+}
+
+func (anno *Annotation) annoInsForward(variant data.Variant, refgene data.Refgene, splicingLen int, dup bool) {
 	cdna, protein := refgene.Cdna, refgene.Protein
 	alt := variant.Alt
 	pos, regionCount := 0, len(refgene.Regions)
@@ -100,7 +148,7 @@ func (anno *Annotation) annoInsForward(variant data.Variant, refgene data.Refgen
 		} else {
 			if region.Typo == "intron" {
 				distance1 := variant.Start - region.Start + 2
-				distance2 := variant.Start - region.Start + 1
+				distance2 := region.End - variant.Start
 				if distance1 <= splicingLen && hasPrev {
 					if distance1 <= 2 {
 						anno.Region = "splicing_site"
@@ -111,6 +159,9 @@ func (anno *Annotation) annoInsForward(variant data.Variant, refgene data.Refgen
 						if refgene.Tag == "cmpl" {
 							anno.SetExon(prevRegion.ExonOrder)
 							anno.NaChange = fmt.Sprintf("c.%d+%dins%s", pos, distance1, alt)
+							if change, ok := getIntronDupChange(variant, region, '+', pos, true); dup && ok {
+								anno.NaChange = change
+							}
 						}
 					} else {
 						anno.Region = strings.Join([]string{prevRegion.Typo, anno.Region}, "_")
@@ -125,6 +176,9 @@ func (anno *Annotation) annoInsForward(variant data.Variant, refgene data.Refgen
 						if refgene.Tag == "cmpl" {
 							anno.SetExon(nextRegion.ExonOrder)
 							anno.NaChange = fmt.Sprintf("c.%d-%dins%s", pos+1, distance2, alt)
+							if change, ok := getIntronDupChange(variant, region, '+', pos, false); dup && ok {
+								anno.NaChange = change
+							}
 						}
 					} else {
 						anno.Region = strings.Join([]string{nextRegion.Typo, anno.Region}, "_")
@@ -151,7 +205,7 @@ func (anno *Annotation) annoInsForward(variant data.Variant, refgene data.Refgen
 				pos += variant.Start - region.Start + 1
 				if refgene.Tag == "cmpl" {
 					anno.SetExon(region.ExonOrder)
-					if refgene.Chrom == "MT" {
+					if data.IsMitochondrion(refgene.Chrom) {
 						anno.annoCdsChangeOfIns(pos, alt, cdna, protein, true)
 					} else {
 						anno.annoCdsChangeOfIns(pos, alt, cdna, protein, false)
@@ -163,9 +217,11 @@ func (anno *Annotation) annoInsForward(variant data.Variant, refgene data.Refgen
 	}
 }
 
-func (anno *Annotation) annoInsBackward(variant data.Variant, refgene data.Refgene, splicingLen int) {
+// annoInsBackward 注释负链转录本上的插入，插入序列取反向互补序列
+func (anno *Annotation) annoInsBackward(variant data.Variant, refgene data.Refgene, splicingLen int, dup bool) {
 	cdna, protein := refgene.Cdna, refgene.Protein
 	alt := variant.Alt
+	alt.ReverseComplement()
 	pos, regionCount := 0, len(refgene.Regions)
 	for i := regionCount - 1; i >= 0; i-- {
 		region := refgene.Regions[i]
@@ -191,6 +247,9 @@ func (anno *Annotation) annoInsBackward(variant data.Variant, refgene data.Refge
 						if refgene.Tag == "cmpl" {
 							anno.SetExon(nextRegion.ExonOrder)
 							anno.NaChange = fmt.Sprintf("c.%d-%dins%s", pos+1, distance1, alt)
+							if change, ok := getIntronDupChange(variant, region, '-', pos, false); dup && ok {
+								anno.NaChange = change
+							}
 						}
 					} else {
 						anno.Region = strings.Join([]string{nextRegion.Typo, anno.Region}, "_")
@@ -204,7 +263,10 @@ func (anno *Annotation) annoInsBackward(variant data.Variant, refgene data.Refge
 					if prevRegion.Typo == "cds" {
 						if refgene.Tag == "cmpl" {
 							anno.SetExon(prevRegion.ExonOrder)
-							anno.NaChange = fmt.Sprintf("c.%d-%dins%s", pos, distance2, alt)
+							anno.NaChange = fmt.Sprintf("c.%d+%dins%s", pos, distance2, alt)
+							if change, ok := getIntronDupChange(variant, region, '-', pos, true); dup && ok {
+								anno.NaChange = change
+							}
 						}
 					} else {
 						anno.Region = strings.Join([]string{prevRegion.Typo, anno.Region}, "_")
@@ -231,18 +293,18 @@ func (anno *Annotation) annoInsBackward(variant data.Variant, refgene data.Refge
 				pos += region.End - variant.Start
 				if refgene.Tag == "cmpl" {
 					anno.SetExon(region.ExonOrder)
-					anno.annoCdsChangeOfIns(pos, alt, cdna, protein, refgene.Chrom == "MT")
+					anno.annoCdsChangeOfIns(pos, alt, cdna, protein, data.IsMitochondrion(refgene.Chrom))
 				}
 			}
 		}
 	}
 }
 
-//AnnoIns 注释Insertion
+// AnnoIns 注释Insertion，插入序列为相邻序列的重复时使用dup表示
 func (anno *Annotation) AnnoIns(ins Snv, refgene data.Refgene, splicingLen int) {
 	if refgene.Strand == '+' {
-		anno.annoInsForward(ins.GetVariant(), refgene, splicingLen)
+		anno.annoInsForward(ins.GetVariant(), refgene, splicingLen, isDuplication(ins))
 	} else {
-		anno.annoInsBackward(ins.GetVariant(), refgene, splicingLen)
+		anno.annoInsBackward(ins.GetVariant(), refgene, splicingLen, isDuplication(ins))
 	}
 }
@@ -0,0 +1,28 @@
+package snv
+
+import "testing"
+
+func TestAnnoInsDup(t *testing.T) {
+	intronIns := newTestSnv(119, 119, "-", "T")
+	cases := []struct {
+		name     string
+		snv      Snv
+		strand   byte
+		naChange string
+		aaChange string
+	}{
+		// c.6_7insGCT即c.4_6dup：正链上108后插入GCT，负链上129后插入AGC
+		{"plus", newTestSnv(108, 108, "-", "GCT"), '+', "c.4_6dup", "p.Ala2dup"},
+		{"minus", newTestSnv(129, 129, "-", "AGC"), '-', "c.4_6dup", "p.Ala2dup"},
+		// 内含子GTAAGT中重复c.12+4的A，负链上119后插入T
+		{"intron dup", variantSnv{Snv: intronIns, variant: intronIns.Variant, dup: true}, '-', "c.12+4dup", ""},
+		{"intron ins", newTestSnv(119, 119, "-", "C"), '-', "c.12+5insG", ""},
+	}
+	for _, c := range cases {
+		var anno Annotation
+		anno.AnnoIns(c.snv, newTestRefgene(c.strand), 5)
+		if anno.NaChange != c.naChange || anno.AaChange != c.aaChange {
+			t.Errorf("%s: got %s %s, want %s %s", c.name, anno.NaChange, anno.AaChange, c.naChange, c.aaChange)
+		}
+	}
+}
@@ -0,0 +1,229 @@
+package snv
+
+import (
+	"fmt"
+	"grandanno/data"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// GatkPhase GATK定相信息：Set为相位集合(PS或PID)，Haplotype为定相后的基因型(GT或PGT，如0|1)
+type GatkPhase struct {
+	Set       string `json:"set"`
+	Haplotype string `json:"haplotype"`
+}
+
+// newGatkPhase 根据FORMAT中的GT/PS或PGT/PID获取定相信息，未定相时返回nil
+func newGatkPhase(gt string, ps string, pgt string, pid string) *GatkPhase {
+	if strings.Contains(gt, "|") && ps != "" && ps != "." {
+		return &GatkPhase{Set: ps, Haplotype: gt}
+	}
+	if strings.Contains(pgt, "|") && pid != "" && pid != "." {
+		return &GatkPhase{Set: pid, Haplotype: pgt}
+	}
+	return nil
+}
+
+// GetHaplotypes 获取等位基因(从1开始的ALT序号)所在的单倍型序号
+func (phase GatkPhase) GetHaplotypes(allele int) (haplotypes []int) {
+	for i, value := range strings.Split(phase.Haplotype, "|") {
+		if value == strconv.Itoa(allele) {
+			haplotypes = append(haplotypes, i)
+		}
+	}
+	return
+}
+
+// PhasedAnnotation 同一密码子中位于同一单倍型上的多个SNV合并后的注释，Variants为参与合并的变异编号
+type PhasedAnnotation struct {
+	Variants []string `json:"variants"`
+	Annotation
+}
+
+// GetPhasedGroups 获取同一样本中位于同一染色体、同一相位集合且同一单倍型上的SNP分组(至少2个)，返回各组SNV在snvs中的下标
+func GetPhasedGroups(snvs Snvs) (groups [][]int) {
+	groupMap := make(map[string][]int)
+	var keys []string
+	for i, snv := range snvs {
+		gatkSnv, ok := snv.(GatkSnv)
+		if !ok || gatkSnv.GetType() != "snp" {
+			continue
+		}
+		for _, sample := range gatkSnv.Samples {
+			if sample.Phase == nil {
+				continue
+			}
+			for _, haplotype := range sample.Phase.GetHaplotypes(gatkSnv.AltIndex + 1) {
+				key := fmt.Sprintf("%s\t%s\t%s\t%d", sample.Name, gatkSnv.Variant.Chrom, sample.Phase.Set, haplotype)
+				if _, ok := groupMap[key]; !ok {
+					keys = append(keys, key)
+				}
+				groupMap[key] = append(groupMap[key], i)
+			}
+		}
+	}
+	visited := make(map[string]bool)
+	for _, key := range keys {
+		group := groupMap[key]
+		members := fmt.Sprint(group)
+		if len(group) < 2 || visited[members] {
+			continue
+		}
+		visited[members] = true
+		groups = append(groups, group)
+	}
+	return
+}
+
+// getGenomePos 获取CDS上的位置pos(从1开始)在基因组上的位置，getCdnaPos的逆运算，位置不在转录本外显子中时返回false
+func getGenomePos(refgene data.Refgene, pos int) (int, bool) {
+	count := len(refgene.Regions)
+	exonLen, cdsStart := 0, 0
+	for k := 0; k < count; k++ {
+		region := refgene.Regions[getRegionIndex(k, count, refgene.Strand)]
+		if region.Typo == "cds" && cdsStart == 0 {
+			cdsStart = exonLen + 1
+		}
+		if region.Typo != "intron" {
+			exonLen += region.End - region.Start + 1
+		}
+	}
+	target, exonLen := pos+cdsStart-1, 0
+	for k := 0; k < count; k++ {
+		region := refgene.Regions[getRegionIndex(k, count, refgene.Strand)]
+		if region.Typo == "intron" {
+			continue
+		}
+		if length := region.End - region.Start + 1; target <= exonLen+length {
+			if refgene.Strand == '-' {
+				return region.End - (target - exonLen) + 1, true
+			}
+			return region.Start + (target - exonLen) - 1, true
+		}
+		exonLen += region.End - region.Start + 1
+	}
+	return 0, false
+}
+
+// GetCodonEnd 获取位置所在密码子在基因组上的终止位置(密码子被内含子分隔时为其各碱基的最大位置，多个转录本中取最大值)，
+// 位置不在任何完整转录本的CDS中时返回false
+func GetCodonEnd(genomePos int, refgenes data.Refgenes) (end int, ok bool) {
+	for _, refgene := range refgenes {
+		if refgene.Tag != "cmpl" {
+			continue
+		}
+		pos, offset, found := getCdnaPos(refgene, genomePos)
+		if !found || offset != 0 || pos < 1 || pos > refgene.Cdna.GetLen() {
+			continue
+		}
+		codon := (pos - 1) / 3 * 3
+		for i := 1; i <= 3; i++ {
+			if p, found := getGenomePos(refgene, codon+i); found && p > end {
+				end, ok = p, true
+			}
+		}
+	}
+	return
+}
+
+// NewPhasedAnnotations 注释一组同一单倍型上的SNP：在各转录本中位于同一密码子的SNP合并后注释密码子的变化
+func NewPhasedAnnotations(snvs Snvs, refgenes data.Refgenes) (phasedAnnos []PhasedAnnotation) {
+	for _, refgene := range refgenes {
+		if refgene.Tag != "cmpl" {
+			continue
+		}
+		codons := make(map[int][]int)
+		var codonIndexes []int
+		for i, snv := range snvs {
+			pos, offset, ok := getCdnaPos(refgene, snv.GetVariant().Start)
+			if !ok || offset != 0 || pos < 1 || pos > refgene.Cdna.GetLen() {
+				continue
+			}
+			codon := (pos - 1) / 3
+			if _, ok := codons[codon]; !ok {
+				codonIndexes = append(codonIndexes, codon)
+			}
+			codons[codon] = append(codons[codon], i)
+		}
+		sort.Ints(codonIndexes)
+		for _, codon := range codonIndexes {
+			if len(codons[codon]) < 2 {
+				continue
+			}
+			var group Snvs
+			for _, index := range codons[codon] {
+				group = append(group, snvs[index])
+			}
+			phasedAnnos = append(phasedAnnos, newPhasedAnnotation(group, refgene, codon))
+		}
+	}
+	return
+}
+
+// newPhasedAnnotation 注释同一密码子中的多个SNP合并后的变化，负链转录本上碱基取互补碱基
+func newPhasedAnnotation(snvs Snvs, refgene data.Refgene, codon int) PhasedAnnotation {
+	phasedAnno := PhasedAnnotation{Annotation: Annotation{
+		Gene:       refgene.Gene,
+		EntrezID:   refgene.EntrezID,
+		Transcript: refgene.Transcript,
+		Region:     "exonic",
+	}}
+	cdna := refgene.Cdna
+	var changes []string
+	positions := make([]int, len(snvs))
+	for i, snv := range snvs {
+		positions[i], _, _ = getCdnaPos(refgene, snv.GetVariant().Start)
+	}
+	sort.Sort(phasedSnvs{snvs: snvs, positions: positions})
+	for i, snv := range snvs {
+		variant := snv.GetVariant()
+		ref, alt := variant.Ref, variant.Alt
+		if refgene.Strand == '-' {
+			ref.ReverseComplement()
+			alt.ReverseComplement()
+		}
+		cdna = cdna.GetSnpSequence(positions[i], alt.GetChar(0))
+		changes = append(changes, fmt.Sprintf("%d%c>%c", positions[i], ref.GetChar(0), alt.GetChar(0)))
+		phasedAnno.Variants = append(phasedAnno.Variants, variant.GetSn())
+	}
+	for _, region := range refgene.Regions {
+		if region.Typo == "cds" && region.Start <= snvs[0].GetVariant().Start && snvs[0].GetVariant().Start <= region.End {
+			phasedAnno.SetExon(region.ExonOrder)
+		}
+	}
+	aa1 := refgene.Protein.GetChar(codon)
+	aa2 := cdna.GetSeq(codon*3, 3).Translate(data.IsMitochondrion(refgene.Chrom)).GetChar(0)
+	if aa1 == aa2 {
+		phasedAnno.Function = "synonymous_snv"
+	} else if aa1 == '*' {
+		phasedAnno.Function = "stoploss"
+	} else if aa2 == '*' {
+		phasedAnno.Function = "stopgain"
+	} else {
+		phasedAnno.Function = "nonsynonymous_snv"
+	}
+	phasedAnno.NaChange = fmt.Sprintf("c.[%s]", strings.Join(changes, ";"))
+	phasedAnno.AaChange = fmt.Sprintf("p.%s%d%s", data.GetOne2Three(aa1), codon+1, data.GetOne2Three(aa2))
+	phasedAnno.SetConsequences(snvs[0], refgene)
+	return phasedAnno
+}
+
+// phasedSnvs 按cDNA位置排序的SNV
+type phasedSnvs struct {
+	snvs      Snvs
+	positions []int
+}
+
+func (snvs phasedSnvs) Len() int {
+	return len(snvs.snvs)
+}
+
+func (snvs phasedSnvs) Less(i, j int) bool {
+	return snvs.positions[i] < snvs.positions[j]
+}
+
+func (snvs phasedSnvs) Swap(i, j int) {
+	snvs.snvs[i], snvs.snvs[j] = snvs.snvs[j], snvs.snvs[i]
+	snvs.positions[i], snvs.positions[j] = snvs.positions[j], snvs.positions[i]
+}
@@ -0,0 +1,18 @@
+package snv
+
+import (
+	"grandanno/data"
+	"testing"
+)
+
+func TestNewPhasedAnnotationsMinusStrand(t *testing.T) {
+	// c.4G>T与c.5C>A：GCT(Ala)->TAT(Tyr)，基因组正链上为132 C>A和131 G>T
+	snvs := Snvs{newTestSnv(132, 132, "C", "A"), newTestSnv(131, 131, "G", "T")}
+	phasedAnnos := NewPhasedAnnotations(snvs, data.Refgenes{newTestRefgene('-')})
+	if len(phasedAnnos) != 1 {
+		t.Fatalf("got %d phased annotations, want 1", len(phasedAnnos))
+	}
+	if anno := phasedAnnos[0]; anno.NaChange != "c.[4G>T;5C>A]" || anno.AaChange != "p.Ala2Tyr" {
+		t.Errorf("got %s %s, want c.[4G>T;5C>A] p.Ala2Tyr", anno.NaChange, anno.AaChange)
+	}
+}
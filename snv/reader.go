@@ -0,0 +1,166 @@
+package snv
+
+import (
+	"bufio"
+	"errors"
+	"grandanno/data"
+	"io"
+	"log"
+	"strconv"
+	"strings"
+)
+
+// ErrUnsortedVcf VCF文件未按坐标排序
+var ErrUnsortedVcf = errors.New("vcf file is not sorted by coordinate")
+
+// vcfOrder 记录检查VCF记录排序时已读取的染色体及当前位置
+type vcfOrder struct {
+	chrom  string
+	pos    int
+	chroms map[string]bool
+}
+
+// check 检查记录是否按坐标排序：同一染色体的记录连续且位置不减
+func (order *vcfOrder) check(line string) error {
+	field := strings.SplitN(line, "\t", 3)
+	if len(field) < 3 {
+		return io.ErrUnexpectedEOF
+	}
+	pos, err := strconv.Atoi(field[1])
+	if err != nil {
+		return err
+	}
+	if field[0] != order.chrom {
+		if order.chroms[field[0]] {
+			return ErrUnsortedVcf
+		}
+		order.chroms[field[0]] = true
+		order.chrom = field[0]
+	} else if pos < order.pos {
+		return ErrUnsortedVcf
+	}
+	order.pos = pos
+	return nil
+}
+
+// IsSortedVcfFile 只读取CHROM/POS检查VCF文件是否按坐标排序，跳过不在当前基因组版本中的染色体
+func IsSortedVcfFile(vcfFile string) (bool, error) {
+	fp, err := data.OpenFile(vcfFile)
+	if err != nil {
+		return false, err
+	}
+	defer fp.Close()
+	reader := bufio.NewReader(fp)
+	order := vcfOrder{chroms: make(map[string]bool)}
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return false, err
+		}
+		eof := err == io.EOF
+		line = strings.TrimSpace(line)
+		if line != "" && line[0] != '#' && data.IsKnownChrom(strings.SplitN(line, "\t", 2)[0]) {
+			if err := order.check(line); err == ErrUnsortedVcf {
+				return false, nil
+			} else if err != nil {
+				return false, err
+			}
+		}
+		if eof {
+			return true, nil
+		}
+	}
+}
+
+// GatkVcfReader 逐条读取GATK VCF文件，同时检查记录是否按坐标排序
+type GatkVcfReader struct {
+	fp      io.ReadCloser
+	reader  *bufio.Reader
+	order   vcfOrder
+	file    string
+	samples []string
+	sexes   map[string]string
+	unknown data.UnknownChroms
+}
+
+// NewGatkVcfReader 打开GATK VCF文件，sexes为样本性别
+func NewGatkVcfReader(vcfFile string, sexes map[string]string) (*GatkVcfReader, error) {
+	log.Printf("start read %s\n", vcfFile)
+	fp, err := data.OpenFile(vcfFile)
+	if err != nil {
+		return nil, err
+	}
+	reader := &GatkVcfReader{
+		fp:      fp,
+		reader:  bufio.NewReader(fp),
+		order:   vcfOrder{chroms: make(map[string]bool)},
+		file:    vcfFile,
+		sexes:   sexes,
+		unknown: make(data.UnknownChroms),
+	}
+	if err := reader.readHeader(); err != nil {
+		fp.Close()
+		return nil, err
+	}
+	return reader, nil
+}
+
+// readHeader 读取VCF表头，获取#CHROM行中的样本名
+func (reader *GatkVcfReader) readHeader() error {
+	for {
+		if b, err := reader.reader.Peek(1); err != nil || b[0] != '#' {
+			return nil
+		}
+		line, err := reader.reader.ReadString('\n')
+		if strings.HasPrefix(line, "#CHROM") {
+			reader.samples = getVcfSamples(strings.TrimSpace(line))
+		}
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+	}
+}
+
+// Samples 获取VCF中的样本名
+func (reader *GatkVcfReader) Samples() []string {
+	return reader.samples
+}
+
+// Read 读取下一条VCF记录的所有SNV，跳过不在当前基因组版本中的染色体；文件结束时返回io.EOF，记录未排序时返回ErrUnsortedVcf
+func (reader *GatkVcfReader) Read() (Snvs, error) {
+	for {
+		line, err := reader.reader.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+		eof := err == io.EOF
+		line = strings.TrimSpace(line)
+		if line != "" && line[0] != '#' {
+			if chrom := strings.SplitN(line, "\t", 2)[0]; !data.IsKnownChrom(chrom) {
+				reader.unknown[chrom]++
+			} else {
+				if err := reader.order.check(line); err != nil {
+					return nil, err
+				}
+				snvs, err := InitGatkSnv(reader.samples, reader.sexes, line)
+				if err != nil {
+					return nil, err
+				}
+				if len(snvs) > 0 {
+					return snvs, nil
+				}
+			}
+		}
+		if eof {
+			return nil, io.EOF
+		}
+	}
+}
+
+// Close 关闭VCF文件，输出被跳过的染色体汇总
+func (reader *GatkVcfReader) Close() error {
+	reader.unknown.Report(reader.file)
+	return reader.fp.Close()
+}
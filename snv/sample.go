@@ -0,0 +1,211 @@
+package snv
+
+import (
+	"grandanno/data"
+	"strconv"
+	"strings"
+)
+
+// 样本在变异位点(某个ALT)上的合子状态：other_alt为样本只含有多等位基因位点的其他ALT
+const (
+	ZygosityHet        = "het"
+	ZygosityHomRef     = "hom_ref"
+	ZygosityHomAlt     = "hom_alt"
+	ZygosityHemizygous = "hemizygous"
+	ZygosityOtherAlt   = "other_alt"
+	ZygosityNoCall     = "no_call"
+)
+
+// Zygosities 所有合子状态
+var Zygosities = []string{
+	ZygosityHet, ZygosityHomRef, ZygosityHomAlt, ZygosityHemizygous, ZygosityOtherAlt, ZygosityNoCall,
+}
+
+// Genotype 解析后的GT：Alleles为等位基因序号(0为REF，未检出为-1)，Phased为是否定相(|分隔)
+type Genotype struct {
+	Alleles []int
+	Phased  bool
+}
+
+// ParseGenotype 解析GT，支持定相/未定相、多等位基因及未检出(.)
+func ParseGenotype(gt string) Genotype {
+	genotype := Genotype{Phased: strings.Contains(gt, "|")}
+	for _, value := range strings.FieldsFunc(gt, func(c rune) bool {
+		return c == '/' || c == '|'
+	}) {
+		allele, err := strconv.Atoi(value)
+		if err != nil || allele < 0 {
+			allele = -1
+		}
+		genotype.Alleles = append(genotype.Alleles, allele)
+	}
+	return genotype
+}
+
+// HasAlt 基因型中是否含有ALT等位基因
+func (genotype Genotype) HasAlt() bool {
+	for _, allele := range genotype.Alleles {
+		if allele > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// GetZygosity 获取第allele个ALT(从1开始)的合子状态，ploidy为样本在该位置的倍性；
+// 倍性为1且只含有该ALT时为hemizygous，部分未检出且含有该ALT时为het
+func (genotype Genotype) GetZygosity(allele int, ploidy int) string {
+	called, count, refCount := 0, 0, 0
+	for _, a := range genotype.Alleles {
+		if a < 0 {
+			continue
+		}
+		called++
+		if a == allele {
+			count++
+		} else if a == 0 {
+			refCount++
+		}
+	}
+	switch {
+	case called == 0:
+		return ZygosityNoCall
+	case count == 0 && refCount == called:
+		return ZygosityHomRef
+	case count == 0:
+		return ZygosityOtherAlt
+	case count == called && ploidy == 1:
+		return ZygosityHemizygous
+	case count == called && called == len(genotype.Alleles):
+		return ZygosityHomAlt
+	default:
+		return ZygosityHet
+	}
+}
+
+// GatkSample GATK VCF中单个样本的基因型信息：Ratio为AD中该ALT的比率，Ploidy为根据性别及PAR得到的倍性，缺失的数值为-1；
+// Inheritance为根据家系中父母基因型得到的遗传方式
+type GatkSample struct {
+	Name        string     `json:"name"`
+	Sex         string     `json:"sex"`
+	Genotype    string     `json:"genotype"`
+	Zygosity    string     `json:"zygosity"`
+	Ploidy      int        `json:"ploidy"`
+	Depth       int        `json:"depth"`
+	Ratio       float64    `json:"ratio"`
+	GQ          int        `json:"gq"`
+	Phase       *GatkPhase `json:"phase,omitempty"`
+	Inheritance []string   `json:"inheritance,omitempty"`
+}
+
+// IsVariant 样本基因型中是否含有ALT等位基因
+func (sample GatkSample) IsVariant() bool {
+	return ParseGenotype(sample.Genotype).HasAlt()
+}
+
+// HasZygosity 样本的合子状态是否为zygosities之一
+func (sample GatkSample) HasZygosity(zygosities []string) bool {
+	for _, zygosity := range zygosities {
+		if sample.Zygosity == zygosity {
+			return true
+		}
+	}
+	return false
+}
+
+// gatkFormat 一个样本的FORMAT信息，ratios为各ALT的变异比率
+type gatkFormat struct {
+	gt, ps, pgt, pid string
+	depth, gq        int
+	ratios           []float64
+}
+
+// newGatkFormat 解析一个样本的FORMAT值，varCount为ALT个数
+func newGatkFormat(formatKeys []string, value string, varCount int) gatkFormat {
+	format := gatkFormat{depth: -1, gq: -1, ratios: make([]float64, varCount)}
+	for i := 0; i < varCount; i++ {
+		format.ratios[i] = float64(-1)
+	}
+	formatValues := strings.Split(value, ":")
+	for i, key := range formatKeys {
+		if i >= len(formatValues) {
+			break
+		}
+		switch key {
+		case "GT":
+			format.gt = formatValues[i]
+		case "PS":
+			format.ps = formatValues[i]
+		case "PGT":
+			format.pgt = formatValues[i]
+		case "PID":
+			format.pid = formatValues[i]
+		case "DP":
+			if dp, err := strconv.Atoi(formatValues[i]); err == nil {
+				format.depth = dp
+			}
+		case "GQ":
+			if gq, err := strconv.Atoi(formatValues[i]); err == nil {
+				format.gq = gq
+			}
+		case "AD":
+			sum := 0
+			varCounts := make([]int, varCount)
+			counts := strings.Split(formatValues[i], ",")
+			for i := 0; i <= varCount && i < len(counts); i++ {
+				if c, err := strconv.Atoi(counts[i]); err == nil {
+					sum += c
+					if i > 0 {
+						varCounts[i-1] = c
+					}
+				}
+			}
+			if sum > 0 {
+				for i := 0; i < varCount; i++ {
+					format.ratios[i] = float64(varCounts[i]) / float64(sum)
+				}
+			}
+		}
+	}
+	return format
+}
+
+// getSample 获取样本在第altIndex个ALT上的基因型信息，ploidy为样本在该位置的倍性
+func (format gatkFormat) getSample(name string, sex string, altIndex int, ploidy int) GatkSample {
+	return GatkSample{
+		Name:     name,
+		Sex:      sex,
+		Genotype: format.gt,
+		Zygosity: ParseGenotype(format.gt).GetZygosity(altIndex+1, ploidy),
+		Ploidy:   ploidy,
+		Depth:    format.depth,
+		Ratio:    format.ratios[altIndex],
+		GQ:       format.gq,
+		Phase:    newGatkPhase(format.gt, format.ps, format.pgt, format.pid),
+	}
+}
+
+// getVcfSamples 获取VCF #CHROM行中的样本名
+func getVcfSamples(line string) []string {
+	field := strings.Split(line, "\t")
+	if len(field) <= 9 {
+		return nil
+	}
+	return field[9:]
+}
+
+// getSampleSex 获取样本的性别，未指定时为未知
+func getSampleSex(sexes map[string]string, name string) string {
+	if sex, ok := sexes[name]; ok {
+		return sex
+	}
+	return data.SexUnknown
+}
+
+// getSampleName 获取第i个样本的名称，表头中没有样本名时以序号命名
+func getSampleName(samples []string, i int) string {
+	if i < len(samples) && samples[i] != "" {
+		return samples[i]
+	}
+	return "sample" + strconv.Itoa(i+1)
+}
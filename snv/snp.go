@@ -99,7 +99,7 @@ func (anno *Annotation) annoSnpForward(variant data.Variant, refgene data.Refgen
 				pos += variant.Start - region.Start + 1
 				if refgene.Tag == "cmpl" {
 					anno.SetExon(region.ExonOrder)
-					if refgene.Chrom == "MT" {
+					if data.IsMitochondrion(refgene.Chrom) {
 						anno.annoCdsChangeOfSnp(pos, alt, cdna, protein, true)
 					} else {
 						anno.annoCdsChangeOfSnp(pos, alt, cdna, protein, false)
@@ -111,11 +111,15 @@ func (anno *Annotation) annoSnpForward(variant data.Variant, refgene data.Refgen
 
 }
 
-func (anno Annotation) annoSnpBackward(variant data.Variant, refgene data.Refgene, splicingLen int) {
+// annoSnpBackward 注释负链转录本上的SNP，碱基取互补碱基
+func (anno *Annotation) annoSnpBackward(variant data.Variant, refgene data.Refgene, splicingLen int) {
 	cdna, protein := refgene.Cdna, refgene.Protein
-	ref, alt := variant.Ref[0], variant.Alt[0]
+	refSeq, altSeq := variant.Ref, variant.Alt
+	refSeq.ReverseComplement()
+	altSeq.ReverseComplement()
+	ref, alt := refSeq.GetChar(0), altSeq.GetChar(0)
 	pos, regionCount := 0, len(refgene.Regions)
-	for i := 0; i < regionCount; i++ {
+	for i := regionCount - 1; i >= 0; i-- {
 		region := refgene.Regions[i]
 		prevRegion, hasPrev := refgene.Regions.GetPrev(i, '-')
 		nextRegion, hasNext := refgene.Regions.GetNext(i, '-')
@@ -179,7 +183,7 @@ func (anno Annotation) annoSnpBackward(variant data.Variant, refgene data.Refgen
 				pos += region.End - variant.Start + 1
 				if refgene.Tag == "cmpl" {
 					anno.SetExon(region.ExonOrder)
-					anno.annoCdsChangeOfSnp(pos, alt, cdna, protein, refgene.Chrom == "MT")
+					anno.annoCdsChangeOfSnp(pos, alt, cdna, protein, data.IsMitochondrion(refgene.Chrom))
 				}
 			}
 		}
@@ -0,0 +1,12 @@
+package snv
+
+import "testing"
+
+func TestAnnoSnpMinusStrand(t *testing.T) {
+	// c.5C>A：GCT(Ala)->GAT(Asp)，基因组正链上为131 G>T
+	var anno Annotation
+	anno.AnnoSnp(newTestSnv(131, 131, "G", "T"), newTestRefgene('-'), 2)
+	if anno.Region != "exonic" || anno.NaChange != "c.5C>A" || anno.AaChange != "p.Ala2Asp" {
+		t.Errorf("got %s %s %s, want exonic c.5C>A p.Ala2Asp", anno.Region, anno.NaChange, anno.AaChange)
+	}
+}
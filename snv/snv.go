@@ -27,3 +27,43 @@ func (snvs Snvs) Less(i, j int) bool {
 func (snvs Snvs) Swap(i, j int) {
 	snvs[i], snvs[j] = snvs[j], snvs[i]
 }
+
+// variantSnv 替换变异信息的SNV，dup为插入序列是否与转录本方向上游相邻的参考序列一致
+type variantSnv struct {
+	Snv
+	variant data.Variant
+	dup     bool
+}
+
+// GetVariant 获取替换后的变异信息
+func (snv variantSnv) GetVariant() data.Variant {
+	return snv.variant
+}
+
+// ShiftedSnv 按转录本方向移位到3'端的SNV：Forward用于正链转录本，Backward用于负链转录本；
+// ForwardDup/BackwardDup为对应方向上插入序列是否为相邻序列的重复
+type ShiftedSnv struct {
+	Snv
+	Forward     data.Variant
+	Backward    data.Variant
+	ForwardDup  bool
+	BackwardDup bool
+}
+
+// getStrandSnv 获取用于该方向转录本注释的SNV
+func getStrandSnv(snv Snv, strand byte) Snv {
+	shifted, ok := snv.(ShiftedSnv)
+	if !ok {
+		return snv
+	}
+	if strand == '-' {
+		return variantSnv{Snv: shifted.Snv, variant: shifted.Backward, dup: shifted.BackwardDup}
+	}
+	return variantSnv{Snv: shifted.Snv, variant: shifted.Forward, dup: shifted.ForwardDup}
+}
+
+// isDuplication 插入是否为转录本方向上游相邻参考序列的重复(仅对移位后的SNV可判断)
+func isDuplication(snv Snv) bool {
+	shifted, ok := snv.(variantSnv)
+	return ok && shifted.dup
+}
@@ -0,0 +1,38 @@
+package snv
+
+import "grandanno/data"
+
+// testSense 测试转录本的正义链序列(101-137)：外显子1 CC|ATGGCTAAACCT，内含子 GTAAGTTTTTAG，
+// 外显子2 GAATGGTAA|GG，CDS编码 MAKPEW*
+const testSense = "CCATGGCTAAACCT" + "GTAAGTTTTTAG" + "GAATGGTAAGG"
+
+// newTestRefgene 创建测试转录本：正链时基因组序列即正义链序列，负链时为其反向互补序列
+func newTestRefgene(strand byte) data.Refgene {
+	refgene := data.Refgene{
+		Chrom:      "1",
+		Strand:     strand,
+		Gene:       "TEST",
+		Transcript: "NM_TEST",
+		ExonStart:  101,
+		ExonEnd:    137,
+		CdsStart:   103,
+		CdsEnd:     135,
+		ExonStarts: []int{101, 127},
+		ExonEnds:   []int{114, 137},
+		Tag:        "cmpl",
+	}
+	mrna := data.Sequence(testSense)
+	if strand == '-' {
+		refgene.ExonStarts, refgene.ExonEnds = []int{101, 124}, []int{111, 137}
+		mrna.ReverseComplement()
+	}
+	refgene.SetRegions()
+	refgene.SetUpDownStream(0)
+	refgene.SetSequence(mrna)
+	return refgene
+}
+
+// newTestSnv 创建测试用SNV
+func newTestSnv(start int, end int, ref string, alt string) GatkSnv {
+	return GatkSnv{Variant: data.Variant{Chrom: "1", Start: start, End: end, Ref: data.Sequence(ref), Alt: data.Sequence(alt)}}
+}
@@ -0,0 +1,418 @@
+package snv
+
+import (
+	"bufio"
+	"errors"
+	"grandanno/data"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Writer SNV注释结果输出接口
+type Writer interface {
+	Write(result Result) error
+	Close() error
+}
+
+// JSONWriter 注释结果逐行输出为JSON
+type JSONWriter struct {
+	fp     *os.File
+	writer *bufio.Writer
+}
+
+// NewJSONWriter 创建JSON输出
+func NewJSONWriter(outFile string) (*JSONWriter, error) {
+	fp, err := os.Create(outFile)
+	if err != nil {
+		return nil, err
+	}
+	return &JSONWriter{fp: fp, writer: bufio.NewWriter(fp)}, nil
+}
+
+// Write 输出一条注释结果
+func (writer *JSONWriter) Write(result Result) error {
+	json, err := data.ConvertToJSON(result)
+	if err != nil {
+		return err
+	}
+	_, err = writer.writer.WriteString(json)
+	return err
+}
+
+// Close 关闭输出文件
+func (writer *JSONWriter) Close() error {
+	if err := writer.writer.Flush(); err != nil {
+		writer.fp.Close()
+		return err
+	}
+	return writer.fp.Close()
+}
+
+// vcfRecord 等待输出的VCF记录：多等位基因记录需收集所有ALT的注释后输出
+type vcfRecord struct {
+	line     string
+	alleles  int
+	done     int
+	annos    []string
+	rsids    []string
+	norms    []string
+	inhs     []string
+	offPanel bool
+}
+
+// VcfWriter 注释结果输出为VCF，注释信息写入INFO字段
+type VcfWriter struct {
+	fp      *os.File
+	writer  *bufio.Writer
+	records map[string]*vcfRecord
+	order   []string
+}
+
+// NewVcfWriter 创建VCF输出，header为原始VCF表头
+func NewVcfWriter(outFile string, header []string) (*VcfWriter, error) {
+	fp, err := os.Create(outFile)
+	if err != nil {
+		return nil, err
+	}
+	writer := &VcfWriter{
+		fp:      fp,
+		writer:  bufio.NewWriter(fp),
+		records: make(map[string]*vcfRecord),
+	}
+	for _, line := range data.InsertVcfHeader(header, data.GetVcfAnnoHeader(), data.GetVcfNormHeader(), data.GetVcfInheritanceHeader(), data.GetVcfOffPanelHeader()) {
+		if _, err := writer.writer.WriteString(line + "\n"); err != nil {
+			fp.Close()
+			return nil, err
+		}
+	}
+	return writer, nil
+}
+
+// GetVcfAnno 获取VCF INFO中的注释信息
+func (anno Annotation) GetVcfAnno(allele string) string {
+	var entrezID string
+	if anno.EntrezID > 0 {
+		entrezID = strconv.Itoa(anno.EntrezID)
+	}
+	values := []string{
+		allele, anno.Gene, entrezID, anno.Transcript, anno.Exon, anno.NaChange, anno.AaChange, anno.Region, anno.Function,
+		data.JoinConsequences(anno.Consequences), anno.Impact,
+	}
+	for i, value := range values {
+		values[i] = data.EscapeVcfInfo(value)
+	}
+	return strings.Join(values, "|")
+}
+
+// Write 输出一条注释结果，多等位基因记录在所有ALT注释完成后输出
+func (writer *VcfWriter) Write(result Result) error {
+	gatkSnv, ok := result.Snv.(GatkSnv)
+	if !ok {
+		return errors.New("vcf output only supports gatk snv")
+	}
+	field := strings.Split(gatkSnv.OtherInfo, "\t")
+	if len(field) < 8 {
+		return errors.New("invalid vcf line: " + gatkSnv.OtherInfo)
+	}
+	alts := strings.Split(field[4], ",")
+	record, ok := writer.records[gatkSnv.OtherInfo]
+	if !ok {
+		// 注释结果按输入顺序输出，新记录开始时之前的记录已不会再有注释(部分ALT可能被过滤)
+		if err := writer.flush(true); err != nil {
+			return err
+		}
+		record = &vcfRecord{line: gatkSnv.OtherInfo, norms: make([]string, len(alts))}
+		for i, alt := range alts {
+			record.norms[i] = "."
+			if alt != "*" {
+				record.alleles++
+			}
+		}
+		writer.records[gatkSnv.OtherInfo] = record
+		writer.order = append(writer.order, gatkSnv.OtherInfo)
+	}
+	for _, anno := range result.Annotations {
+		record.annos = append(record.annos, anno.GetVcfAnno(alts[gatkSnv.AltIndex]))
+	}
+	for _, rsid := range strings.Split(result.Dbsnp, ";") {
+		if rsid != "" && !containsString(record.rsids, rsid) {
+			record.rsids = append(record.rsids, rsid)
+		}
+	}
+	for _, sample := range gatkSnv.Samples {
+		if len(sample.Inheritance) > 0 {
+			record.inhs = append(record.inhs, strings.Join([]string{
+				data.EscapeVcfInfo(alts[gatkSnv.AltIndex]),
+				data.EscapeVcfInfo(sample.Name),
+				strings.Join(sample.Inheritance, "&"),
+			}, "|"))
+		}
+	}
+	if result.Normalized != nil && gatkSnv.AltIndex < len(record.norms) {
+		record.norms[gatkSnv.AltIndex] = data.EscapeVcfInfo(result.Normalized.GetSn())
+	}
+	record.offPanel = record.offPanel || result.OffPanel
+	record.done++
+	return writer.flush(false)
+}
+
+// containsString 切片中是否包含字符串
+func containsString(strs []string, str string) bool {
+	for _, s := range strs {
+		if s == str {
+			return true
+		}
+	}
+	return false
+}
+
+// flush 按输入顺序输出已完成的VCF记录，all为true时输出全部记录
+func (writer *VcfWriter) flush(all bool) error {
+	for len(writer.order) > 0 {
+		record := writer.records[writer.order[0]]
+		if !all && record.done < record.alleles {
+			break
+		}
+		line := record.line
+		if len(record.annos) > 0 {
+			line = data.AddVcfInfo(line, data.VcfAnnoKey, strings.Join(record.annos, ","))
+		}
+		for _, norm := range record.norms {
+			if norm != "." {
+				line = data.AddVcfInfo(line, data.VcfNormKey, strings.Join(record.norms, ","))
+				break
+			}
+		}
+		if record.offPanel {
+			line = data.AddVcfFlag(line, data.VcfOffPanelKey)
+		}
+		if len(record.inhs) > 0 {
+			line = data.AddVcfInfo(line, data.VcfInheritanceKey, strings.Join(record.inhs, ","))
+		}
+		if len(record.rsids) > 0 {
+			line = data.SetVcfID(line, strings.Join(record.rsids, ";"))
+		}
+		if _, err := writer.writer.WriteString(line + "\n"); err != nil {
+			return err
+		}
+		delete(writer.records, writer.order[0])
+		writer.order = writer.order[1:]
+	}
+	return nil
+}
+
+// Close 输出剩余记录并关闭输出文件
+func (writer *VcfWriter) Close() error {
+	err := writer.flush(true)
+	if err == nil {
+		err = writer.writer.Flush()
+	}
+	if e := writer.fp.Close(); err == nil {
+		err = e
+	}
+	return err
+}
+
+// TsvColumns TSV默认输出列
+var TsvColumns = []string{
+	"chrom", "start", "end", "ref", "alt", "depth", "qual", "filter", "ratio",
+	"sample", "genotype", "zygosity", "gq", "inheritance",
+	"gene", "entrez_id", "transcript", "exon", "na_change", "aa_change", "region", "function",
+	"consequence", "impact", "normalized", "off_panel",
+}
+
+// getSampleValues 获取各样本的值，以逗号分隔，缺失值为"."
+func getSampleValues(gatkSnv GatkSnv, getValue func(sample GatkSample) string) string {
+	values := make([]string, len(gatkSnv.Samples))
+	for i, sample := range gatkSnv.Samples {
+		if values[i] = getValue(sample); values[i] == "" {
+			values[i] = "."
+		}
+	}
+	return strings.Join(values, ",")
+}
+
+// getTsvValue 获取TSV列的值，depth/ratio/sample/sex/genotype/zygosity/ploidy/gq/inheritance为各样本的值，以逗号分隔
+func getTsvValue(column string, result Result, anno Annotation) (value string, ok bool) {
+	variant := result.Snv.GetVariant()
+	gatkSnv, isGatk := result.Snv.(GatkSnv)
+	switch column {
+	case "chrom":
+		value = variant.Chrom
+	case "start":
+		value = strconv.Itoa(variant.Start)
+	case "end":
+		value = strconv.Itoa(variant.End)
+	case "ref":
+		value = variant.Ref.String()
+	case "alt":
+		value = variant.Alt.String()
+	case "depth":
+		if isGatk {
+			value = getSampleValues(gatkSnv, func(sample GatkSample) string {
+				if sample.Depth < 0 {
+					return ""
+				}
+				return strconv.Itoa(sample.Depth)
+			})
+		}
+	case "qual":
+		if isGatk && gatkSnv.Information.Qual >= 0 {
+			value = strconv.FormatFloat(gatkSnv.Information.Qual, 'f', -1, 64)
+		}
+	case "filter":
+		if isGatk {
+			value = gatkSnv.Information.GatkFilter
+		}
+	case "ratio":
+		if isGatk {
+			value = getSampleValues(gatkSnv, func(sample GatkSample) string {
+				if sample.Ratio < 0 {
+					return ""
+				}
+				return strconv.FormatFloat(sample.Ratio, 'f', 4, 64)
+			})
+		}
+	case "sample":
+		if isGatk {
+			value = getSampleValues(gatkSnv, func(sample GatkSample) string { return sample.Name })
+		}
+	case "genotype":
+		if isGatk {
+			value = getSampleValues(gatkSnv, func(sample GatkSample) string { return sample.Genotype })
+		}
+	case "zygosity":
+		if isGatk {
+			value = getSampleValues(gatkSnv, func(sample GatkSample) string { return sample.Zygosity })
+		}
+	case "sex":
+		if isGatk {
+			value = getSampleValues(gatkSnv, func(sample GatkSample) string { return sample.Sex })
+		}
+	case "ploidy":
+		if isGatk {
+			value = getSampleValues(gatkSnv, func(sample GatkSample) string { return strconv.Itoa(sample.Ploidy) })
+		}
+	case "allele_frequency":
+		if isGatk && gatkSnv.Information.AlleleFreq >= 0 {
+			value = strconv.FormatFloat(gatkSnv.Information.AlleleFreq, 'f', -1, 64)
+		}
+	case "inheritance":
+		if isGatk {
+			value = getSampleValues(gatkSnv, func(sample GatkSample) string { return strings.Join(sample.Inheritance, "&") })
+		}
+	case "gq":
+		if isGatk {
+			value = getSampleValues(gatkSnv, func(sample GatkSample) string {
+				if sample.GQ < 0 {
+					return ""
+				}
+				return strconv.Itoa(sample.GQ)
+			})
+		}
+	case "gene":
+		value = anno.Gene
+	case "entrez_id":
+		if anno.EntrezID > 0 {
+			value = strconv.Itoa(anno.EntrezID)
+		}
+	case "transcript":
+		value = anno.Transcript
+	case "exon":
+		value = anno.Exon
+	case "na_change":
+		value = anno.NaChange
+	case "aa_change":
+		value = anno.AaChange
+	case "region":
+		value = anno.Region
+	case "function":
+		value = anno.Function
+	case "consequence":
+		value = data.JoinConsequences(anno.Consequences)
+	case "impact":
+		value = anno.Impact
+	case "most_severe_consequence":
+		value = result.Consequence
+	case "off_panel":
+		if result.OffPanel {
+			value = "yes"
+		}
+	case "normalized":
+		if result.Normalized != nil {
+			value = result.Normalized.GetSn()
+		}
+	case "phased_na_change":
+		phasedAnno, _ := result.GetPhasedAnnotation(anno.Transcript)
+		value = phasedAnno.NaChange
+	case "phased_aa_change":
+		phasedAnno, _ := result.GetPhasedAnnotation(anno.Transcript)
+		value = phasedAnno.AaChange
+	case "phased_function":
+		phasedAnno, _ := result.GetPhasedAnnotation(anno.Transcript)
+		value = phasedAnno.Function
+	default:
+		return "", false
+	}
+	if value == "" {
+		value = "."
+	}
+	return value, true
+}
+
+// TsvWriter 注释结果输出为TSV，每个变异的每个转录本注释输出一行
+type TsvWriter struct {
+	fp      *os.File
+	writer  *bufio.Writer
+	columns []string
+}
+
+// NewTsvWriter 创建TSV输出，columns为空时输出默认列
+func NewTsvWriter(outFile string, columns []string) (*TsvWriter, error) {
+	if len(columns) == 0 {
+		columns = TsvColumns
+	}
+	for _, column := range columns {
+		if _, ok := getTsvValue(column, Result{Snv: GatkSnv{}}, Annotation{}); !ok {
+			return nil, errors.New("unknown tsv column: " + column)
+		}
+	}
+	fp, err := os.Create(outFile)
+	if err != nil {
+		return nil, err
+	}
+	writer := &TsvWriter{fp: fp, writer: bufio.NewWriter(fp), columns: columns}
+	if _, err := writer.writer.WriteString("#" + strings.Join(columns, "\t") + "\n"); err != nil {
+		fp.Close()
+		return nil, err
+	}
+	return writer, nil
+}
+
+// Write 输出一条注释结果
+func (writer *TsvWriter) Write(result Result) error {
+	values := make([]string, len(writer.columns))
+	annos := result.Annotations
+	if len(annos) == 0 {
+		annos = Annotations{Annotation{}}
+	}
+	for _, anno := range annos {
+		for i, column := range writer.columns {
+			values[i], _ = getTsvValue(column, result, anno)
+		}
+		if _, err := writer.writer.WriteString(strings.Join(values, "\t") + "\n"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close 关闭输出文件
+func (writer *TsvWriter) Close() error {
+	err := writer.writer.Flush()
+	if e := writer.fp.Close(); err == nil {
+		err = e
+	}
+	return err
+}